@@ -1,51 +1,56 @@
 package rocketmq_client
 
 import (
-	"errors"
+	"context"
 	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
 	"github.com/apache/rocketmq-clients/golang/v5/credentials"
-	"os"
+	"log/slog"
 	"strings"
 )
 
 type Config struct {
-	Endpoint         string           //必填
-	NameSpace        string           //必填
-	ConsumerGroup    string           //使用消费者时，必填
-	AccessKey        string           //可选
-	AccessSecret     string           //可选
-	LogPath          string           //官方rocketmq日志文件路径，默认为/tmp
-	LogStdout        bool             //是否在终端输出官方rocketmq日志，输出的话则不会记录日志文件
-	Debug            bool             //是否在终端输出本客户端的debug信息
-	DebugHandlerFunc debugHandlerFunc //本客户端的debug信息处理方法，不管debug开没开，有debug信息的时候都会调用
-	FlowColor        *string          //流量染色标识，为nil则表示不启用流量染色功能，生产者时表示流量染色标识，消费者时表示当前系统的染色标识
-	FlowColorBase    *bool            //当前环境是否是基准环境，消费者使用，为nil则忽略，是基准系统时，可以匹配流量标识为空字符串的消息
+	Endpoint            string              //必填
+	NameSpace           string              //必填
+	ConsumerGroup       string              //使用消费者时，必填
+	AccessKey           string              //可选
+	AccessSecret        string              //可选
+	CredentialsProvider CredentialsProvider //凭证提供者，可选，设置后忽略AccessKey和AccessSecret，运行中会定期获取最新凭证
+	LogPath             string              //官方rocketmq日志文件路径，默认为/tmp，只对当前实例生效
+	LogStdout           bool                //是否在终端输出官方rocketmq日志，输出的话则不会记录日志文件，只对当前实例生效
+	Debug               bool                //是否在终端输出本客户端的debug信息
+	DebugHandlerFunc    debugHandlerFunc    //本客户端的debug信息处理方法，不管debug开没开，有debug信息的时候都会调用
+	Logger              *slog.Logger        //本客户端的结构化日志记录器，可选，设置后Debug和DebugHandlerFunc不再生效，官方rocketmq日志也会转发到这里
+	FlowColor           *string             //流量染色标识，为nil则表示不启用流量染色功能，生产者时表示流量染色标识，消费者时表示当前系统的染色标识
+	FlowColorBase       *bool               //当前环境是否是基准环境，消费者使用，为nil则忽略，是基准系统时，可以匹配流量标识为空字符串的消息
+	TLS                 *TLSConfig          //连接服务端的TLS配置，可选，对Endpoint中的地址和从这些地址查询到的路由地址生效，同一地址不能同时使用不同的TLS配置
 }
 
 func checkCfg(cfg *Config) error {
-	if cfg.LogStdout {
-		os.Setenv("mq.consoleAppender.enabled", "true")
-	} else {
-		os.Setenv("mq.consoleAppender.enabled", "false")
-	}
 	if strings.Trim(cfg.LogPath, "") == "" {
 		cfg.LogPath = "/tmp"
 	}
-	os.Setenv("rocketmq.client.logRoot", cfg.LogPath)
-	rmq_client.ResetLogger()
 
 	if strings.Trim(cfg.Endpoint, "") == "" {
-		return errors.New("Endpoint不能为空")
+		return newError(CodeInvalidConfig, ErrMsgRequired, "Endpoint")
 	}
 
 	if strings.Trim(cfg.NameSpace, "") == "" {
-		return errors.New("NameSpace不能为空")
+		return newError(CodeInvalidConfig, ErrMsgRequired, "NameSpace")
+	}
+
+	if cfg.TLS != nil {
+		if _, err := cfg.TLS.build(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func getRmqCfg(cfg *Config) *rmq_client.Config {
-	return &rmq_client.Config{
+// getRmqCfg 转换为官方客户端的配置，同时登记Endpoint中地址的TLS配置，见registerEndpointTLS；
+// 设置了CredentialsProvider时传给官方客户端的是占位凭证，见credentialsSession；
+// release释放TLS登记和凭证会话，需在官方客户端注销后或创建失败时调用
+func getRmqCfg(ctx context.Context, cfg *Config) (rc *rmq_client.Config, release func(), err error) {
+	rc = &rmq_client.Config{
 		Endpoint:      cfg.Endpoint,
 		NameSpace:     cfg.NameSpace,
 		ConsumerGroup: cfg.ConsumerGroup,
@@ -55,4 +60,22 @@ func getRmqCfg(cfg *Config) *rmq_client.Config {
 			SecurityToken: "",
 		},
 	}
+	releaseTLS, err := registerEndpointTLS(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	release = releaseTLS
+	if cfg.CredentialsProvider != nil {
+		session, err := newCredentialsSession(ctx, cfg)
+		if err != nil {
+			releaseTLS()
+			return nil, nil, err
+		}
+		rc.Credentials = session.placeholder()
+		release = func() {
+			session.close()
+			releaseTLS()
+		}
+	}
+	return rc, release, nil
 }
@@ -0,0 +1,686 @@
+package rocketmq_client
+
+import (
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/util/gconv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigNodeName 配置文件和gf配置中rocketmq配置所在的节点名
+const ConfigNodeName = "rocketmq"
+
+// DefaultInstanceName 默认实例名，环境变量未声明实例列表时使用
+const DefaultInstanceName = "default"
+
+// InstanceConfig 单个实例的配置，可以从配置文件、环境变量或gf配置加载
+// 配置文件示例(yaml)：
+//
+//	rocketmq:
+//	  default:
+//	    endpoint: 127.0.0.1:18081
+//	    nameSpace: test
+//	    consumerGroup: cg_test_demo
+//	    producer:
+//	      maxAttempts: 3
+//	      topics:
+//	        test_normal_demo: NORMAL
+//	        test_fifo_demo: FIFO
+//	    consumer:
+//	      awaitDuration: 5s
+//	      maxMessageNum: 10
+//	      invisibleDuration: 10s
+//	      subscriptions:
+//	        test_normal_demo:
+//	          expression: "*"
+//	        test_fifo_demo:
+//	          expression: "a > 1"
+//	          type: SQL92
+type InstanceConfig struct {
+	Name          string          `json:"-"`             //实例名，即配置中的节点名
+	Endpoint      string          `json:"endpoint"`      //必填
+	NameSpace     string          `json:"nameSpace"`     //必填
+	ConsumerGroup string          `json:"consumerGroup"` //配置了consumer时必填
+	AccessKey     string          `json:"accessKey"`     //可选
+	AccessSecret  string          `json:"accessSecret"`  //可选
+	LogPath       string          `json:"logPath"`       //可选
+	LogStdout     bool            `json:"logStdout"`     //可选
+	Debug         bool            `json:"debug"`         //可选
+	FlowColor     *string         `json:"flowColor"`     //可选
+	FlowColorBase *bool           `json:"flowColorBase"` //可选
+	TLS           *TLSConfig      `json:"tls"`           //TLS配置，可选
+	Producer      *ProducerConfig `json:"producer"`      //生产者配置，可选
+	Consumer      *ConsumerConfig `json:"consumer"`      //消费者配置，可选
+}
+
+// ProducerConfig 生产者配置
+type ProducerConfig struct {
+	Topics      map[string]TopicType `json:"topics"`      //主题及其类型，不区分大小写，类型为空时为NORMAL
+	MaxAttempts int32                `json:"maxAttempts"` //重试次数，可选
+	Preflight   PreflightMode        `json:"preflight"`   //启动前预检模式，WARN或FAIL_FAST，可选
+}
+
+// ConsumerConfig 消费者配置
+type ConsumerConfig struct {
+	AwaitDuration     string                         `json:"awaitDuration"`     //如5s，可选
+	MaxMessageNum     int32                          `json:"maxMessageNum"`     //可选
+	InvisibleDuration string                         `json:"invisibleDuration"` //如10s，可选
+	Subscriptions     map[string]*SubscriptionConfig `json:"subscriptions"`     //订阅关系，key为topic，必填
+	Preflight         PreflightMode                  `json:"preflight"`         //启动前预检模式，WARN或FAIL_FAST，可选
+	Concurrency       int                            `json:"concurrency"`       //并发消费的协程数，可选
+	Fifo              bool                           `json:"fifo"`              //是否按消息组顺序消费，可选
+	PrefetchSize      int                            `json:"prefetchSize"`      //预取缓冲区能放的消息数，可选
+	AckMode           AckMode                        `json:"ackMode"`           //确认模式，MANUAL或AUTO，可选
+	NackStrategy      NackStrategy                   `json:"nackStrategy"`      //自动确认模式下消费失败的处理方式，EXPIRE或BACKOFF，可选
+	NackBackoff       []string                       `json:"nackBackoff"`       //BACKOFF时按投递次数取的重新投递延迟，如["10s","1m"]，可选
+	Retry             *RetryConfig                   `json:"retry"`             //重新投递策略，可选
+	RetryRules        []*RetryConfig                 `json:"retryRules"`        //按主题和tag指定的重新投递策略，需设置topic，可选
+	StopTimeout       string                         `json:"stopTimeout"`       //注销时等待消费方法返回的时间，如30s，可选
+	LeaseRenewal      bool                           `json:"leaseRenewal"`      //是否自动续期消费中消息的不可见时间，可选
+	MaxProcessingTime string                         `json:"maxProcessingTime"` //自动续期时单条消息最长的消费时间，如10m，可选
+	BatchSize         int                            `json:"batchSize"`         //按批消费时每批最多的消息数，可选
+	BatchWindow       string                         `json:"batchWindow"`       //按批消费时凑批等待的最长时间，如100ms，可选
+	Poll              *PollConfig                    `json:"poll"`              //接收消息的轮询策略，未配置的项使用DefaultPollPolicy，可选
+}
+
+// PollConfig 接收消息的轮询策略配置
+type PollConfig struct {
+	IdleDelay       string  `json:"idleDelay"`       //没有新消息时等待的时间，如1s
+	ErrorInitial    string  `json:"errorInitial"`    //第一次接收失败后等待的时间，如200ms
+	ErrorMax        string  `json:"errorMax"`        //接收失败后最长等待的时间，如30s
+	ErrorMultiplier float64 `json:"errorMultiplier"` //连续失败时等待时间的倍数
+	Jitter          float64 `json:"jitter"`          //等待时间随机抖动的比例，0~1
+}
+
+// policy 转换为轮询策略，需先通过Validate校验
+func (s *PollConfig) policy() PollPolicy {
+	p := DefaultPollPolicy
+	if s.IdleDelay != "" {
+		p.IdleDelay, _ = time.ParseDuration(strings.TrimSpace(s.IdleDelay))
+	}
+	if d, _ := parseConfigDuration("", s.ErrorInitial); d > 0 {
+		p.ErrorInitial = d
+	}
+	if d, _ := parseConfigDuration("", s.ErrorMax); d > 0 {
+		p.ErrorMax = d
+	}
+	if s.ErrorMultiplier > 0 {
+		p.ErrorMultiplier = s.ErrorMultiplier
+	}
+	if s.Jitter > 0 {
+		p.Jitter = s.Jitter
+	}
+	return p
+}
+
+// RetryConfig 重新投递策略配置，设置了delays时按列表取延迟，否则按initial指数退避
+type RetryConfig struct {
+	Topic       string   `json:"topic"`       //主题，只在retryRules中使用
+	Tag         string   `json:"tag"`         //tag，只在retryRules中使用，为空时匹配主题的所有消息
+	MaxAttempts int32    `json:"maxAttempts"` //最多投递的次数，可选，0为不限制
+	Delays      []string `json:"delays"`      //按投递次数取的延迟，如["10s","1m"]
+	Initial     string   `json:"initial"`     //指数退避的第一次延迟，如1s
+	Max         string   `json:"max"`         //指数退避的最大延迟，可选
+	Multiplier  float64  `json:"multiplier"`  //指数退避的倍数，可选，默认2
+}
+
+// validate 校验重新投递策略配置，key为配置项的名称
+func (s *RetryConfig) validate(key string) error {
+	if s.MaxAttempts < 0 {
+		return newError(CodeInvalidConfig, ErrMsgNegative, key+".maxAttempts", s.MaxAttempts)
+	}
+	for i, v := range s.Delays {
+		if _, err := parseConfigDuration(key+".delays["+strconv.Itoa(i)+"]", v); err != nil {
+			return err
+		}
+	}
+	if len(s.Delays) == 0 && strings.TrimSpace(s.Initial) == "" {
+		return newError(CodeInvalidConfig, ErrMsgRequired, key+".delays/"+key+".initial")
+	}
+	if _, err := parseConfigDuration(key+".initial", s.Initial); err != nil {
+		return err
+	}
+	if _, err := parseConfigDuration(key+".max", s.Max); err != nil {
+		return err
+	}
+	if s.Multiplier < 0 {
+		return newError(CodeInvalidConfig, ErrMsgNegative, key+".multiplier", s.Multiplier)
+	}
+	return nil
+}
+
+// policy 按配置创建重新投递策略，需先校验
+func (s *RetryConfig) policy() RetryPolicy {
+	if len(s.Delays) > 0 {
+		delays := make([]time.Duration, 0, len(s.Delays))
+		for _, v := range s.Delays {
+			d, _ := parseConfigDuration("", v)
+			delays = append(delays, d)
+		}
+		return NewFixedRetryPolicy(s.MaxAttempts, delays...)
+	}
+	initial, _ := parseConfigDuration("", s.Initial)
+	max, _ := parseConfigDuration("", s.Max)
+	return NewExponentialRetryPolicy(s.MaxAttempts, initial, max, s.Multiplier)
+}
+
+// SubscriptionConfig 订阅配置
+type SubscriptionConfig struct {
+	Expression string `json:"expression"` //过滤表达式，为空时为*
+	Type       string `json:"type"`       //TAG或SQL92，为空时为TAG
+}
+
+// Validate 校验实例配置，校验前会规范化主题类型
+func (s *InstanceConfig) Validate() error {
+	s.normalize()
+	if strings.TrimSpace(s.Endpoint) == "" {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "endpoint")
+	}
+	if strings.TrimSpace(s.NameSpace) == "" {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "nameSpace")
+	}
+	if s.TLS != nil {
+		if _, err := s.TLS.build(); err != nil {
+			return err
+		}
+	}
+	if s.Producer != nil {
+		if s.Producer.MaxAttempts < 0 {
+			return newError(CodeInvalidConfig, ErrMsgNegative, "producer.maxAttempts", s.Producer.MaxAttempts)
+		}
+		if !isValidPreflightMode(s.Producer.Preflight) {
+			return newError(CodeInvalidConfig, ErrMsgInvalidPreflightMode, "producer.preflight", s.Producer.Preflight)
+		}
+		for topic, topicType := range s.Producer.Topics {
+			if strings.TrimSpace(topic) == "" {
+				return newError(CodeInvalidConfig, ErrMsgRequired, "producer.topics.<topic>")
+			}
+			if !isValidTopicType(topicType) {
+				return newError(CodeInvalidConfig, ErrMsgInvalidTopicType, "producer.topics."+topic, topicType)
+			}
+		}
+	}
+	if s.Consumer != nil {
+		if strings.TrimSpace(s.ConsumerGroup) == "" {
+			return newError(CodeInvalidConfig, ErrMsgConsumerGroupRequired)
+		}
+		if _, err := parseConfigDuration("consumer.awaitDuration", s.Consumer.AwaitDuration); err != nil {
+			return err
+		}
+		if _, err := parseConfigDuration("consumer.invisibleDuration", s.Consumer.InvisibleDuration); err != nil {
+			return err
+		}
+		if _, err := parseConfigDuration("consumer.stopTimeout", s.Consumer.StopTimeout); err != nil {
+			return err
+		}
+		if _, err := parseConfigDuration("consumer.maxProcessingTime", s.Consumer.MaxProcessingTime); err != nil {
+			return err
+		}
+		if _, err := parseConfigDuration("consumer.batchWindow", s.Consumer.BatchWindow); err != nil {
+			return err
+		}
+		if poll := s.Consumer.Poll; poll != nil {
+			//idleDelay可以为0，即不等待
+			if d, err := time.ParseDuration(strings.TrimSpace(poll.IdleDelay)); poll.IdleDelay != "" && (err != nil || d < 0) {
+				return newError(CodeInvalidConfig, ErrMsgInvalidDuration, "consumer.poll.idleDelay", poll.IdleDelay)
+			}
+			for key, v := range map[string]string{"errorInitial": poll.ErrorInitial, "errorMax": poll.ErrorMax} {
+				if _, err := parseConfigDuration("consumer.poll."+key, v); err != nil {
+					return err
+				}
+			}
+			if err := poll.policy().check(); err != nil {
+				return err
+			}
+		}
+		if s.Consumer.AckMode != "" && !isValidAckMode(s.Consumer.AckMode) {
+			return newError(CodeInvalidConfig, ErrMsgInvalidAckMode, "consumer.ackMode", s.Consumer.AckMode)
+		}
+		if s.Consumer.NackStrategy != "" && !isValidNackStrategy(s.Consumer.NackStrategy) {
+			return newError(CodeInvalidConfig, ErrMsgInvalidNackStrategy, "consumer.nackStrategy", s.Consumer.NackStrategy)
+		}
+		if s.Consumer.Retry != nil {
+			if err := s.Consumer.Retry.validate("consumer.retry"); err != nil {
+				return err
+			}
+		}
+		for i, rule := range s.Consumer.RetryRules {
+			key := "consumer.retryRules[" + strconv.Itoa(i) + "]"
+			if rule == nil || strings.TrimSpace(rule.Topic) == "" {
+				return newError(CodeInvalidConfig, ErrMsgRequired, key+".topic")
+			}
+			if err := rule.validate(key); err != nil {
+				return err
+			}
+		}
+		for i, v := range s.Consumer.NackBackoff {
+			if _, err := parseConfigDuration("consumer.nackBackoff["+strconv.Itoa(i)+"]", v); err != nil {
+				return err
+			}
+		}
+		if s.Consumer.MaxMessageNum < 0 {
+			return newError(CodeInvalidConfig, ErrMsgNegative, "consumer.maxMessageNum", s.Consumer.MaxMessageNum)
+		}
+		if s.Consumer.Concurrency < 0 {
+			return newError(CodeInvalidConfig, ErrMsgNegative, "consumer.concurrency", s.Consumer.Concurrency)
+		}
+		if s.Consumer.PrefetchSize < 0 {
+			return newError(CodeInvalidConfig, ErrMsgNegative, "consumer.prefetchSize", s.Consumer.PrefetchSize)
+		}
+		if !isValidPreflightMode(s.Consumer.Preflight) {
+			return newError(CodeInvalidConfig, ErrMsgInvalidPreflightMode, "consumer.preflight", s.Consumer.Preflight)
+		}
+		if len(s.Consumer.Subscriptions) == 0 {
+			return newError(CodeInvalidConfig, ErrMsgRequired, "consumer.subscriptions")
+		}
+		for topic, sub := range s.Consumer.Subscriptions {
+			if strings.TrimSpace(topic) == "" {
+				return newError(CodeInvalidConfig, ErrMsgRequired, "consumer.subscriptions.<topic>")
+			}
+			//表达式语法只在开启预检时校验，见Preflight
+			if _, err := sub.filterExpression("consumer.subscriptions." + topic); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalize 规范化主题类型：去掉空白并转大写，为空时为NORMAL，文件、gcfg和环境变量加载时都经过这里
+func (s *InstanceConfig) normalize() {
+	if s.Producer == nil {
+		return
+	}
+	for topic, topicType := range s.Producer.Topics {
+		s.Producer.Topics[topic] = normalizeTopicType(topicType)
+	}
+}
+
+func normalizeTopicType(topicType TopicType) TopicType {
+	if t := TopicType(strings.ToUpper(strings.TrimSpace(string(topicType)))); t != "" {
+		return t
+	}
+	return TopicNormal
+}
+
+// Config 转换为客户端配置
+func (s *InstanceConfig) Config() *Config {
+	return &Config{
+		Endpoint:      s.Endpoint,
+		NameSpace:     s.NameSpace,
+		ConsumerGroup: s.ConsumerGroup,
+		AccessKey:     s.AccessKey,
+		AccessSecret:  s.AccessSecret,
+		LogPath:       s.LogPath,
+		LogStdout:     s.LogStdout,
+		Debug:         s.Debug,
+		FlowColor:     s.FlowColor,
+		FlowColorBase: s.FlowColorBase,
+		TLS:           s.TLS,
+	}
+}
+
+// ProducerOptionFuncs 转换为生产者选项，未配置producer时返回空
+func (s *InstanceConfig) ProducerOptionFuncs() []ProducerOptionFunc {
+	if s.Producer == nil {
+		return nil
+	}
+	var oFuncs []ProducerOptionFunc
+	if len(s.Producer.Topics) > 0 {
+		oFuncs = append(oFuncs, WithProducerOptionTopicTypes(s.Producer.Topics))
+	}
+	if s.Producer.MaxAttempts > 0 {
+		oFuncs = append(oFuncs, WithProducerOptionMaxAttempts(s.Producer.MaxAttempts))
+	}
+	if s.Producer.Preflight != PreflightDisabled {
+		oFuncs = append(oFuncs, WithProducerOptionPreflight(s.Producer.Preflight))
+	}
+	return oFuncs
+}
+
+// ConsumerOptionFuncs 转换为消费者选项，未配置consumer时返回空，需先通过Validate校验
+func (s *InstanceConfig) ConsumerOptionFuncs() []ConsumerOptionFunc {
+	if s.Consumer == nil {
+		return nil
+	}
+	var oFuncs []ConsumerOptionFunc
+	if d, _ := parseConfigDuration("", s.Consumer.AwaitDuration); d > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionAwaitDuration(d))
+	}
+	if d, _ := parseConfigDuration("", s.Consumer.InvisibleDuration); d > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionInvisibleDuration(d))
+	}
+	if d, _ := parseConfigDuration("", s.Consumer.StopTimeout); d > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionStopTimeout(d))
+	}
+	if s.Consumer.MaxMessageNum > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionMaxMessageNum(s.Consumer.MaxMessageNum))
+	}
+	if s.Consumer.Concurrency > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionConcurrency(s.Consumer.Concurrency))
+	}
+	if s.Consumer.PrefetchSize > 0 {
+		oFuncs = append(oFuncs, WithConsumerOptionPrefetchSize(s.Consumer.PrefetchSize))
+	}
+	if s.Consumer.Fifo {
+		oFuncs = append(oFuncs, WithConsumerOptionFifo(true))
+	}
+	if s.Consumer.BatchSize > 0 || s.Consumer.BatchWindow != "" {
+		d, _ := parseConfigDuration("", s.Consumer.BatchWindow)
+		oFuncs = append(oFuncs, WithConsumerOptionBatch(s.Consumer.BatchSize, d))
+	}
+	if s.Consumer.Poll != nil {
+		oFuncs = append(oFuncs, WithConsumerOptionPollPolicy(s.Consumer.Poll.policy()))
+	}
+	if s.Consumer.LeaseRenewal {
+		d, _ := parseConfigDuration("", s.Consumer.MaxProcessingTime)
+		oFuncs = append(oFuncs, WithConsumerOptionLeaseRenewal(d))
+	}
+	if s.Consumer.AckMode != "" {
+		oFuncs = append(oFuncs, WithConsumerOptionAckMode(s.Consumer.AckMode))
+	}
+	if s.Consumer.Retry != nil {
+		oFuncs = append(oFuncs, WithConsumerOptionRetryPolicy(s.Consumer.Retry.policy()))
+	}
+	for _, rule := range s.Consumer.RetryRules {
+		oFuncs = append(oFuncs, WithConsumerOptionRetryRule(rule.Topic, rule.Tag, rule.policy()))
+	}
+	if s.Consumer.NackStrategy != "" {
+		var backoff []time.Duration
+		for _, v := range s.Consumer.NackBackoff {
+			if d, _ := parseConfigDuration("", v); d > 0 {
+				backoff = append(backoff, d)
+			}
+		}
+		oFuncs = append(oFuncs, WithConsumerOptionNack(s.Consumer.NackStrategy, backoff...))
+	}
+	subExpressions := make(map[string]*FilterExpression, len(s.Consumer.Subscriptions))
+	for topic, sub := range s.Consumer.Subscriptions {
+		subExpressions[topic], _ = sub.filterExpression("")
+	}
+	oFuncs = append(oFuncs, WithConsumerOptionSubExpressions(subExpressions))
+	if s.Consumer.Preflight != PreflightDisabled {
+		oFuncs = append(oFuncs, WithConsumerOptionPreflight(s.Consumer.Preflight))
+	}
+	return oFuncs
+}
+
+func (s *SubscriptionConfig) filterExpression(field string) (*FilterExpression, error) {
+	expression := "*"
+	if s != nil && strings.TrimSpace(s.Expression) != "" {
+		expression = s.Expression
+	}
+	var subType string
+	if s != nil {
+		subType = strings.ToUpper(strings.TrimSpace(s.Type))
+	}
+	switch subType {
+	case "", "TAG":
+		return NewFilterExpression(expression), nil
+	case "SQL92":
+		return NewFilterExpressionWithType(expression, rmq_client.SQL92), nil
+	}
+	return nil, newError(CodeInvalidConfig, ErrMsgInvalidFilterType, field, s.Type)
+}
+
+func isValidTopicType(topicType TopicType) bool {
+	switch topicType {
+	case TopicNormal, TopicFIFO, TopicDelay, TopicTransaction:
+		return true
+	}
+	return false
+}
+
+func parseConfigDuration(key string, value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, newError(CodeInvalidConfig, ErrMsgInvalidDuration, key, value)
+	}
+	if d <= 0 {
+		return 0, newError(CodeInvalidConfig, ErrMsgNonPositiveDuration, key, value)
+	}
+	return d, nil
+}
+
+// LoadConfigFile 从配置文件加载所有实例配置，支持yaml、toml、json等格式，按文件后缀识别
+func LoadConfigFile(path string) (map[string]*InstanceConfig, error) {
+	j, err := gjson.Load(path, true)
+	if err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgConfigReadFailed, path).wrap(err)
+	}
+	return decodeInstanceConfigs(j.Get(ConfigNodeName).Map())
+}
+
+// LoadConfigContent 从配置内容加载所有实例配置，自动识别yaml、toml、json等格式
+func LoadConfigContent(content []byte) (map[string]*InstanceConfig, error) {
+	j, err := gjson.LoadContent(content, true)
+	if err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgConfigReadFailed, "content").wrap(err)
+	}
+	return decodeInstanceConfigs(j.Get(ConfigNodeName).Map())
+}
+
+// decodeInstanceConfigs 把rocketmq节点下的配置解析成实例配置并校验
+func decodeInstanceConfigs(data map[string]any) (map[string]*InstanceConfig, error) {
+	if len(data) == 0 {
+		return nil, newError(CodeInvalidConfig, ErrMsgConfigNodeMissing, ConfigNodeName)
+	}
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	instances := make(map[string]*InstanceConfig, len(data))
+	for _, name := range names {
+		ic := &InstanceConfig{}
+		if err := gconv.Struct(data[name], ic); err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgConfigDecodeFailed, name).wrap(err)
+		}
+		ic.Name = name
+		if err := ic.Validate(); err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgInstanceInvalid, name).wrap(err)
+		}
+		instances[name] = ic
+	}
+	return instances, nil
+}
+
+// LoadConfigEnv 从环境变量加载实例配置
+// <PREFIX>_INSTANCES为逗号分隔的实例名列表，未设置时只有一个名为default的实例，变量名为<PREFIX>_<KEY>；
+// 设置了时变量名为<PREFIX>_<实例名大写>_<KEY>。
+// KEY支持：ENDPOINT、NAMESPACE、CONSUMER_GROUP、ACCESS_KEY、ACCESS_SECRET、LOG_PATH、LOG_STDOUT、DEBUG、
+// FLOW_COLOR、FLOW_COLOR_BASE、TLS_CA_FILE、TLS_CERT_FILE、TLS_KEY_FILE、TLS_SERVER_NAME、TLS_INSECURE_SKIP_VERIFY、
+// TLS_PLAINTEXT、PRODUCER_TOPICS（如topic1:NORMAL,topic2:FIFO）、PRODUCER_MAX_ATTEMPTS、
+// CONSUMER_AWAIT_DURATION、CONSUMER_MAX_MESSAGE_NUM、CONSUMER_INVISIBLE_DURATION、CONSUMER_STOP_TIMEOUT、
+// CONSUMER_CONCURRENCY、CONSUMER_PREFETCH_SIZE、CONSUMER_FIFO、CONSUMER_ACK_MODE、CONSUMER_NACK_STRATEGY、
+// CONSUMER_NACK_BACKOFF（逗号分隔，如10s,1m）、CONSUMER_LEASE_RENEWAL、CONSUMER_MAX_PROCESSING_TIME、
+// CONSUMER_BATCH_SIZE、CONSUMER_BATCH_WINDOW、CONSUMER_POLL_IDLE_DELAY、CONSUMER_POLL_ERROR_INITIAL、CONSUMER_POLL_ERROR_MAX、
+// CONSUMER_SUBSCRIPTIONS（如topic1=*;topic2=SQL92:a > 1）。
+func LoadConfigEnv(prefix string) (map[string]*InstanceConfig, error) {
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	if prefix == "" {
+		return nil, newError(CodeInvalidArgument, ErrMsgRequired, "prefix")
+	}
+	var names []string
+	for _, name := range strings.Split(os.Getenv(prefix+"_INSTANCES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	instances := make(map[string]*InstanceConfig)
+	if len(names) == 0 {
+		ic, err := loadInstanceConfigEnv(prefix + "_")
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgInstanceInvalid, DefaultInstanceName).wrap(err)
+		}
+		ic.Name = DefaultInstanceName
+		instances[DefaultInstanceName] = ic
+		return instances, nil
+	}
+	for _, name := range names {
+		ic, err := loadInstanceConfigEnv(prefix + "_" + strings.ToUpper(name) + "_")
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgInstanceInvalid, name).wrap(err)
+		}
+		ic.Name = name
+		instances[name] = ic
+	}
+	return instances, nil
+}
+
+func loadInstanceConfigEnv(prefix string) (*InstanceConfig, error) {
+	var (
+		err error
+		ic  = &InstanceConfig{
+			Endpoint:      os.Getenv(prefix + "ENDPOINT"),
+			NameSpace:     os.Getenv(prefix + "NAMESPACE"),
+			ConsumerGroup: os.Getenv(prefix + "CONSUMER_GROUP"),
+			AccessKey:     os.Getenv(prefix + "ACCESS_KEY"),
+			AccessSecret:  os.Getenv(prefix + "ACCESS_SECRET"),
+			LogPath:       os.Getenv(prefix + "LOG_PATH"),
+		}
+	)
+	if ic.LogStdout, err = envBool(prefix + "LOG_STDOUT"); err != nil {
+		return nil, err
+	}
+	if ic.Debug, err = envBool(prefix + "DEBUG"); err != nil {
+		return nil, err
+	}
+	if v, ok := os.LookupEnv(prefix + "FLOW_COLOR"); ok {
+		ic.FlowColor = &v
+	}
+	if _, ok := os.LookupEnv(prefix + "FLOW_COLOR_BASE"); ok {
+		b, err := envBool(prefix + "FLOW_COLOR_BASE")
+		if err != nil {
+			return nil, err
+		}
+		ic.FlowColorBase = &b
+	}
+	if ic.TLS, err = loadTLSConfigEnv(prefix + "TLS_"); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv(prefix + "PRODUCER_TOPICS"); v != "" {
+		ic.Producer = &ProducerConfig{Topics: map[string]TopicType{}}
+		for _, item := range strings.Split(v, ",") {
+			topic, topicType, _ := strings.Cut(strings.TrimSpace(item), ":")
+			ic.Producer.Topics[strings.TrimSpace(topic)] = TopicType(topicType)
+		}
+	}
+	if v := os.Getenv(prefix + "PRODUCER_MAX_ATTEMPTS"); v != "" {
+		if ic.Producer == nil {
+			ic.Producer = &ProducerConfig{}
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgInvalidInt, prefix+"PRODUCER_MAX_ATTEMPTS", v)
+		}
+		ic.Producer.MaxAttempts = int32(n)
+	}
+
+	if v := os.Getenv(prefix + "CONSUMER_SUBSCRIPTIONS"); v != "" {
+		ic.Consumer = &ConsumerConfig{
+			AwaitDuration:     os.Getenv(prefix + "CONSUMER_AWAIT_DURATION"),
+			InvisibleDuration: os.Getenv(prefix + "CONSUMER_INVISIBLE_DURATION"),
+			StopTimeout:       os.Getenv(prefix + "CONSUMER_STOP_TIMEOUT"),
+			MaxProcessingTime: os.Getenv(prefix + "CONSUMER_MAX_PROCESSING_TIME"),
+			BatchWindow:       os.Getenv(prefix + "CONSUMER_BATCH_WINDOW"),
+			Subscriptions:     map[string]*SubscriptionConfig{},
+		}
+		for _, item := range strings.Split(v, ";") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			topic, expression, _ := strings.Cut(item, "=")
+			sub := &SubscriptionConfig{Expression: strings.TrimSpace(expression)}
+			if t, e, ok := strings.Cut(sub.Expression, ":"); ok && strings.EqualFold(t, "SQL92") {
+				sub.Type, sub.Expression = "SQL92", strings.TrimSpace(e)
+			}
+			ic.Consumer.Subscriptions[strings.TrimSpace(topic)] = sub
+		}
+		if n := os.Getenv(prefix + "CONSUMER_MAX_MESSAGE_NUM"); n != "" {
+			num, err := strconv.ParseInt(n, 10, 32)
+			if err != nil {
+				return nil, newError(CodeInvalidConfig, ErrMsgInvalidInt, prefix+"CONSUMER_MAX_MESSAGE_NUM", n)
+			}
+			ic.Consumer.MaxMessageNum = int32(num)
+		}
+		if ic.Consumer.Fifo, err = envBool(prefix + "CONSUMER_FIFO"); err != nil {
+			return nil, err
+		}
+		if ic.Consumer.LeaseRenewal, err = envBool(prefix + "CONSUMER_LEASE_RENEWAL"); err != nil {
+			return nil, err
+		}
+		poll := &PollConfig{
+			IdleDelay:    os.Getenv(prefix + "CONSUMER_POLL_IDLE_DELAY"),
+			ErrorInitial: os.Getenv(prefix + "CONSUMER_POLL_ERROR_INITIAL"),
+			ErrorMax:     os.Getenv(prefix + "CONSUMER_POLL_ERROR_MAX"),
+		}
+		if *poll != (PollConfig{}) {
+			ic.Consumer.Poll = poll
+		}
+		ic.Consumer.AckMode = AckMode(strings.ToUpper(os.Getenv(prefix + "CONSUMER_ACK_MODE")))
+		ic.Consumer.NackStrategy = NackStrategy(strings.ToUpper(os.Getenv(prefix + "CONSUMER_NACK_STRATEGY")))
+		if v := os.Getenv(prefix + "CONSUMER_NACK_BACKOFF"); v != "" {
+			for _, d := range strings.Split(v, ",") {
+				ic.Consumer.NackBackoff = append(ic.Consumer.NackBackoff, strings.TrimSpace(d))
+			}
+		}
+		for key, field := range map[string]*int{
+			"CONSUMER_CONCURRENCY":   &ic.Consumer.Concurrency,
+			"CONSUMER_PREFETCH_SIZE": &ic.Consumer.PrefetchSize,
+			"CONSUMER_BATCH_SIZE":    &ic.Consumer.BatchSize,
+		} {
+			if n := os.Getenv(prefix + key); n != "" {
+				num, err := strconv.Atoi(n)
+				if err != nil {
+					return nil, newError(CodeInvalidConfig, ErrMsgInvalidInt, prefix+key, n)
+				}
+				*field = num
+			}
+		}
+	}
+	return ic, ic.Validate()
+}
+
+// loadTLSConfigEnv 读取TLS相关的环境变量，都没有设置时返回nil
+func loadTLSConfigEnv(prefix string) (*TLSConfig, error) {
+	var (
+		err error
+		tc  = &TLSConfig{
+			CAFile:     os.Getenv(prefix + "CA_FILE"),
+			CertFile:   os.Getenv(prefix + "CERT_FILE"),
+			KeyFile:    os.Getenv(prefix + "KEY_FILE"),
+			ServerName: os.Getenv(prefix + "SERVER_NAME"),
+		}
+	)
+	if tc.InsecureSkipVerify, err = envBool(prefix + "INSECURE_SKIP_VERIFY"); err != nil {
+		return nil, err
+	}
+	if tc.Plaintext, err = envBool(prefix + "PLAINTEXT"); err != nil {
+		return nil, err
+	}
+	if *tc == (TLSConfig{}) {
+		return nil, nil
+	}
+	return tc, nil
+}
+
+func envBool(key string) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, newError(CodeInvalidConfig, ErrMsgInvalidBool, key, v)
+	}
+	return b, nil
+}
@@ -0,0 +1,118 @@
+package rocketmq_client
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfigYaml = `
+rocketmq:
+  default:
+    endpoint: 127.0.0.1:18081
+    nameSpace: test
+    consumerGroup: cg_test
+    producer:
+      topics:
+        t_normal: normal
+        t_empty: ""
+        t_fifo: " FIFO "
+    consumer:
+      awaitDuration: 5s
+      subscriptions:
+        t_normal:
+          expression: "a||b"
+        t_fifo:
+          expression: "a > 1"
+          type: sql92
+`
+
+func checkTestTopics(t *testing.T, ic *InstanceConfig) {
+	t.Helper()
+	want := map[string]TopicType{"t_normal": TopicNormal, "t_empty": TopicNormal, "t_fifo": TopicFIFO}
+	for topic, topicType := range want {
+		if got := ic.Producer.Topics[topic]; got != topicType {
+			t.Errorf("topic %s type = %q, want %q", topic, got, topicType)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := instances[DefaultInstanceName]
+	if ic == nil || ic.Name != DefaultInstanceName || ic.Endpoint != "127.0.0.1:18081" {
+		t.Fatalf("unexpected instance %+v", ic)
+	}
+	checkTestTopics(t, ic)
+	if len(ic.ConsumerOptionFuncs()) == 0 {
+		t.Fatal("ConsumerOptionFuncs is empty")
+	}
+}
+
+func TestLoadConfigContentInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no node":      "foo: 1",
+		"no endpoint":  "rocketmq:\n  a:\n    nameSpace: test",
+		"bad type":     "rocketmq:\n  a:\n    endpoint: x\n    nameSpace: test\n    producer:\n      topics:\n        t: BAD",
+		"no group":     "rocketmq:\n  a:\n    endpoint: x\n    nameSpace: test\n    consumer:\n      subscriptions:\n        t:\n          expression: '*'",
+		"bad duration": "rocketmq:\n  a:\n    endpoint: x\n    nameSpace: test\n    consumerGroup: g\n    consumer:\n      awaitDuration: 5x\n      subscriptions:\n        t:\n          expression: '*'",
+	} {
+		if _, err := LoadConfigContent([]byte(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		} else if ErrorCodeOf(err) != CodeInvalidConfig {
+			t.Errorf("%s: code = %s, want %s", name, ErrorCodeOf(err), CodeInvalidConfig)
+		}
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	t.Setenv("MQTEST_INSTANCES", "a")
+	t.Setenv("MQTEST_A_ENDPOINT", "127.0.0.1:18081")
+	t.Setenv("MQTEST_A_NAMESPACE", "test")
+	t.Setenv("MQTEST_A_CONSUMER_GROUP", "cg_test")
+	t.Setenv("MQTEST_A_PRODUCER_TOPICS", "t_normal:normal,t_empty,t_fifo:FIFO")
+	t.Setenv("MQTEST_A_CONSUMER_SUBSCRIPTIONS", "t_normal=a||b;t_fifo=SQL92:a > 1")
+	t.Setenv("MQTEST_A_CONSUMER_CONCURRENCY", "4")
+	t.Setenv("MQTEST_A_CONSUMER_POLL_IDLE_DELAY", "0s")
+	instances, err := LoadConfigEnv("mqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := instances["a"]
+	if ic == nil || ic.Consumer == nil || ic.Consumer.Concurrency != 4 {
+		t.Fatalf("unexpected instance %+v", ic)
+	}
+	checkTestTopics(t, ic)
+	if sub := ic.Consumer.Subscriptions["t_fifo"]; sub.Type != "SQL92" || sub.Expression != "a > 1" {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+
+	t.Setenv("MQTEST_A_CONSUMER_CONCURRENCY", "x")
+	if _, err = LoadConfigEnv("mqtest"); err == nil {
+		t.Fatal("expected error for invalid int")
+	}
+}
+
+func TestLoadConfig4Gf(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent(testConfigYaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := gcfg.Instance().GetAdapter()
+	gcfg.Instance().SetAdapter(adapter)
+	defer gcfg.Instance().SetAdapter(old)
+	instances, err := LoadConfig4Gf(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestTopics(t, instances[DefaultInstanceName])
+}
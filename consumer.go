@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -56,11 +59,98 @@ func WithConsumerOptionSubExpressions(SubExpressions map[string]*FilterExpressio
 	}
 }
 
+func WithConsumerOptionConcurrency(Concurrency int) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.Concurrency = Concurrency
+	}
+}
+
+func WithConsumerOptionPrefetchSize(PrefetchSize int) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.PrefetchSize = PrefetchSize
+	}
+}
+
+func WithConsumerOptionFifo(Fifo bool) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.Fifo = Fifo
+	}
+}
+
+func WithConsumerOptionAckMode(AckMode AckMode) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.AckMode = AckMode
+	}
+}
+
+// WithConsumerOptionNack 设置自动确认模式下消费失败的处理方式，backoff只在strategy为NackBackoff时生效，为空时使用DefaultNackBackoff
+func WithConsumerOptionNack(strategy NackStrategy, backoff ...time.Duration) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.NackStrategy = strategy
+		if len(backoff) > 0 {
+			o.NackBackoff = backoff
+		}
+	}
+}
+
+func WithConsumerOptionRetryPolicy(RetryPolicy RetryPolicy) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.RetryPolicy = RetryPolicy
+	}
+}
+
+// WithConsumerOptionRetryRule 为主题或主题的tag指定重新投递策略，tag为空或*时匹配主题的所有消息，可多次调用
+func WithConsumerOptionRetryRule(topic, tag string, policy RetryPolicy) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.RetryRules = append(o.RetryRules, RetryRule{Topic: topic, Tag: tag, Policy: policy})
+	}
+}
+
+func WithConsumerOptionStopTimeout(StopTimeout time.Duration) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.StopTimeout = StopTimeout
+	}
+}
+
+func WithConsumerOptionPreflight(mode PreflightMode) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.Preflight = mode
+	}
+}
+
+// WithConsumerOptionHealth 启动成功后把消费者注册到健康检查，name为注册的名称
+func WithConsumerOptionHealth(checker *HealthChecker, name string) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.healthChecker = checker
+		o.healthName = name
+	}
+}
+
 type ConsumerOptions struct {
 	AwaitDuration     time.Duration                //接收消息的超时时间，默认5秒，实际值为设置值+3秒
 	MaxMessageNum     int32                        //每次接收的消息数量，默认10
 	InvisibleDuration time.Duration                //接收到的消息的不可见时间，默认10秒
 	SubExpressions    map[string]*FilterExpression //订阅表达式，必填，key为topic，简单消费类型只支持tag和sql匹配
+	Concurrency       int                          //并发消费的协程数，默认1，即按接收顺序逐条消费，运行中可通过SimpleConsumer.SetConcurrency调整
+	Fifo              bool                         //是否按消息组顺序消费，用于FIFO主题，不同消息组并发消费（并发数见Concurrency），同一消息组按顺序逐条消费，消费失败时该组暂停推进直到重新投递
+	PrefetchSize      int                          //预取缓冲区能放的消息数，默认等于MaxMessageNum，缓冲区放不下一批消息时暂停接收
+	StopTimeout       time.Duration                //注销时等待正在执行的消费方法返回的时间，默认30秒，超时后仍会注销并返回错误
+	AckMode           AckMode                      //消息确认模式，默认AckManual
+	NackStrategy      NackStrategy                 //自动确认模式下消费方法返回错误时的处理方式，默认NackExpire
+	NackBackoff       []time.Duration              //NackStrategy为NackBackoff时按投递次数取的重新投递延迟，默认DefaultNackBackoff
+	RetryPolicy       RetryPolicy                  //消费失败且没有确认时的重新投递策略，可选，设置后手动确认模式也生效，未设置时消息在不可见时间过后重新投递
+	RetryRules        []RetryRule                  //按主题和tag指定的重新投递策略，优先于RetryPolicy
+	PollPolicy        PollPolicy                   //接收消息的轮询策略，默认DefaultPollPolicy
+	BatchSize         int                          //按批消费时每批最多的消息数，默认等于MaxMessageNum
+	BatchWindow       time.Duration                //按批消费时凑批等待的最长时间，默认0，即只取缓冲区中已有的消息
+	LeaseRenewal      bool                         //是否自动续期消费中消息的不可见时间，消费方法返回前在剩余不到2/3时续期InvisibleDuration
+	MaxProcessingTime time.Duration                //自动续期时单条消息最长的消费时间，超过后不再续期并取消消费方法的ctx，0为不限制
+	DeadLetter        *DeadLetter                  //客户端死信转发，可选，未设置时重试次数用完或不可重试的消息直接确认
+	Dedup             *Dedup                       //消费幂等去重，可选
+	Preflight         PreflightMode                //启动前预检模式，可选，默认不预检，会检查连通性、订阅主题的路由、消费者分组和订阅表达式语法
+	router            *Router                      //路由，可选
+	healthChecker     *HealthChecker               //健康检查，可选
+	healthName        string                       //在健康检查中注册的名称
 }
 
 type Consumer interface {
@@ -70,56 +160,181 @@ type Consumer interface {
 }
 
 type defaultConsumer struct {
-	mv       *rmq_client.MessageView
-	consumer rmq_client.SimpleConsumer
+	mv         *rmq_client.MessageView
+	consumer   rmq_client.SimpleConsumer
+	mu         sync.Mutex   //调整不可见时间会更新消息的receipt handle，和确认、续期串行执行
+	acked      atomic.Bool  //是否已确认成功，自动确认模式下不重复确认
+	changed    atomic.Bool  //消费方法内是否调整过不可见时间，调整过时消费失败不再按重新投递策略调整
+	leaseUntil atomic.Int64 //消息不可见的截止时间，UnixNano，调整不可见时间成功后更新
+}
+
+// newDefaultConsumer 消费一条消息时传给消费方法的Consumer，不可见时间从接收时开始计算
+func newDefaultConsumer(mv *rmq_client.MessageView, consumer rmq_client.SimpleConsumer, received time.Time, invisibleDuration time.Duration) *defaultConsumer {
+	c := &defaultConsumer{mv: mv, consumer: consumer}
+	c.leaseUntil.Store(received.Add(invisibleDuration).UnixNano())
+	return c
 }
 
-func (s defaultConsumer) Ack(ctx context.Context) error {
-	return s.consumer.Ack(ctx, s.mv)
+// invisibleUntil 消息不可见的截止时间，消费失败后按重新投递策略调整过时为调整后的时间
+func (s *defaultConsumer) invisibleUntil() time.Time {
+	return time.Unix(0, s.leaseUntil.Load())
 }
 
-func (s defaultConsumer) ChangeInvisibleDuration(invisibleDuration time.Duration) error {
-	return s.consumer.ChangeInvisibleDuration(s.mv, invisibleDuration)
+func (s *defaultConsumer) Ack(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.consumer.Ack(ctx, s.mv)
+	if err == nil {
+		s.acked.Store(true)
+	}
+	return err
+}
+
+func (s *defaultConsumer) ChangeInvisibleDuration(invisibleDuration time.Duration) error {
+	s.changed.Store(true)
+	return s.renew(invisibleDuration)
 }
 
-func (s defaultConsumer) ChangeInvisibleDurationAsync(invisibleDuration time.Duration) {
-	s.consumer.ChangeInvisibleDurationAsync(s.mv, invisibleDuration)
+func (s *defaultConsumer) ChangeInvisibleDurationAsync(invisibleDuration time.Duration) {
+	s.changed.Store(true)
+	go func() {
+		_ = s.renew(invisibleDuration)
+	}()
 	return
 }
 
+// renew 调整不可见时间，成功时更新截止时间
+func (s *defaultConsumer) renew(invisibleDuration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.consumer.ChangeInvisibleDuration(s.mv, invisibleDuration)
+	if err == nil {
+		s.leaseUntil.Store(time.Now().Add(invisibleDuration).UnixNano())
+	}
+	return err
+}
+
 // ConsumeFunc 消费方法
-// 方法内消费成功时需要调用consumer.Ack()；
-// 消费时间可能超过消费者MaxMessageNum设置的时间时，可调用consumer.ChangeInvisibleDuration()或consumer.ChangeInvisibleDurationAsync()方法调整消息消费超时时间；
+// 手动确认模式（默认）下，方法内消费成功时需要调用consumer.Ack()；自动确认模式（WithConsumerOptionAckMode(AckAuto)）下，返回nil时自动确认，返回错误时按NackStrategy处理；
+// 返回错误且没有确认时，按重新投递策略（WithConsumerOptionRetryPolicy、WithConsumerOptionRetryRule）调整下次投递的时间；
+// 消费时间可能超过消费者InvisibleDuration设置的时间时，可调用consumer.ChangeInvisibleDuration()或consumer.ChangeInvisibleDurationAsync()方法调整消息消费超时时间，
+// 或通过WithConsumerOptionLeaseRenewal开启自动续期；
 type ConsumeFunc func(ctx context.Context, msg *rmq_client.MessageView, consumer Consumer) error
 
 // SimpleConsume 简单消费类型消费
-func SimpleConsume(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func(), err error) {
-	err = checkCfg(cfg)
+// ctx结束或调用stopFunc后不再接收新消息；stopFunc会等待正在执行的消费方法返回（最长StopTimeout）后注销消费者，返回等待超时或注销失败的错误；
+// ctx结束时会自动注销消费者，消费方法收到的ctx也会结束
+func SimpleConsume(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	c, err := startSimpleConsumer(ctx, cfg, consumeFunc, oFunc...)
 	if err != nil {
 		return
 	}
+	stopFunc = c.Stop
+	return
+}
 
-	o := ConsumerOptions{
-		AwaitDuration:     time.Second * 5,
-		MaxMessageNum:     10,
-		InvisibleDuration: time.Second * 10,
+// startSimpleConsumer 启动简单消费者和接收循环
+func startSimpleConsumer(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (c *simpleConsumer, err error) {
+	return startConsumer(ctx, cfg, consumeFunc, nil, oFunc...)
+}
+
+// startConsumer 启动简单消费者和接收循环，batchConsumeFunc不为nil时按批消费
+func startConsumer(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, batchConsumeFunc BatchConsumeFunc, oFunc ...ConsumerOptionFunc) (c *simpleConsumer, err error) {
+	err = checkCfg(cfg)
+	if err != nil {
+		return
 	}
-	options := &o
-	if len(oFunc) > 0 {
-		for _, f := range oFunc {
-			f(options)
+
+	options := newConsumerOptions(oFunc...)
+
+	if options.router != nil {
+		if err = options.router.check(); err != nil {
+			logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+			return
+		}
+		if len(options.SubExpressions) == 0 {
+			options.SubExpressions = options.router.SubExpressions()
 		}
 	}
-
 	if len(options.SubExpressions) == 0 {
-		err = errors.New("SubExpressions不能为空")
-		debugLog(cfg, "消费者参数不合法:%v", err)
+		err = newError(CodeInvalidConfig, ErrMsgRequired, "SubExpressions")
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
 		return
 	}
 
+	if options.Concurrency < 1 {
+		err = newError(CodeInvalidConfig, ErrMsgNonPositive, "Concurrency", options.Concurrency)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if !isValidAckMode(options.AckMode) {
+		err = newError(CodeInvalidConfig, ErrMsgInvalidAckMode, "AckMode", options.AckMode)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if !isValidNackStrategy(options.NackStrategy) {
+		err = newError(CodeInvalidConfig, ErrMsgInvalidNackStrategy, "NackStrategy", options.NackStrategy)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	for i, rule := range options.RetryRules {
+		if rule.Topic == "" || rule.Policy == nil {
+			err = newError(CodeInvalidConfig, ErrMsgRequired, fmt.Sprintf("RetryRules[%d].Topic/Policy", i))
+			logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+			return
+		}
+	}
+	if options.MaxProcessingTime < 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNegative, "MaxProcessingTime", options.MaxProcessingTime)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if options.Dedup != nil && options.Dedup.Store == nil {
+		err = newError(CodeInvalidConfig, ErrMsgRequired, "Dedup.Store")
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if err = checkDeadLetter(options.DeadLetter); err != nil {
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if options.PrefetchSize < 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNegative, "PrefetchSize", options.PrefetchSize)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if options.PrefetchSize == 0 {
+		options.PrefetchSize = int(options.MaxMessageNum)
+	}
+	if err = options.PollPolicy.check(); err != nil {
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if options.BatchSize < 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNegative, "BatchSize", options.BatchSize)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if options.BatchWindow < 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNegative, "BatchWindow", options.BatchWindow)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+	if batchConsumeFunc != nil {
+		//按批消费时按返回的结果确认
+		options.AckMode = AckAuto
+		if options.BatchSize == 0 {
+			options.BatchSize = int(options.MaxMessageNum)
+		}
+	}
+
 	if strings.Trim(cfg.ConsumerGroup, "") == "" {
-		err = errors.New("ConsumerGroup不能为空")
-		debugLog(cfg, "消费者参数不合法:%v", err)
+		err = newError(CodeInvalidConfig, ErrMsgRequired, "ConsumerGroup")
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者参数不合法", attrError(err))
+		return
+	}
+
+	if err = runPreflight(ctx, cfg, options.Preflight, opConsumerStart, nil, options.SubExpressions); err != nil {
 		return
 	}
 
@@ -145,49 +360,230 @@ func SimpleConsume(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oF
 		subExpressions[k] = rmq_client.NewFilterExpressionWithType(v.Expression, v.ExpressionType)
 	}
 
-	consumer, err := rmq_client.NewSimpleConsumer(
-		getRmqCfg(cfg),
-		rmq_client.WithAwaitDuration(options.AwaitDuration),
-		rmq_client.WithSubscriptionExpressions(subExpressions),
-	)
+	rc, releaseCredentials, err := getRmqCfg(ctx, cfg)
+	if err != nil {
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "初始化消费者失败", attrError(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			releaseCredentials()
+		}
+	}()
+	consumer, err := newSdkClient(cfg, func() (rmq_client.SimpleConsumer, error) {
+		return rmq_client.NewSimpleConsumer(
+			rc,
+			rmq_client.WithAwaitDuration(options.AwaitDuration),
+			rmq_client.WithSubscriptionExpressions(subExpressions),
+		)
+	})
 	if err != nil {
-		debugLog(cfg, "初始化消费者失败:%v", err)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "初始化消费者失败", attrError(err))
 		return nil, err
 	}
 
 	err = consumer.Start()
 	if err != nil {
-		debugLog(cfg, "消费者启动失败:%v", err)
+		logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "消费者启动失败", attrError(err))
 		return nil, err
 	}
+	logEvent(ctx, cfg, slog.LevelInfo, opConsumerStart, "消费者启动成功")
+	return runSimpleConsumer(ctx, cfg, options, consumer, consumeFunc, batchConsumeFunc, releaseCredentials), nil
+}
 
-	stopFunc = func() {
-		err1 := consumer.GracefulStop()
-		if err1 != nil {
-			debugLog(cfg, "消费者注销失败:%v", err1)
+// newConsumerOptions 默认的消费者选项，按oFunc修改
+func newConsumerOptions(oFunc ...ConsumerOptionFunc) *ConsumerOptions {
+	options := &ConsumerOptions{
+		AwaitDuration:     time.Second * 5,
+		MaxMessageNum:     10,
+		InvisibleDuration: time.Second * 10,
+		StopTimeout:       time.Second * 30,
+		Concurrency:       1,
+		AckMode:           AckManual,
+		NackStrategy:      NackExpire,
+		NackBackoff:       DefaultNackBackoff,
+		PollPolicy:        DefaultPollPolicy,
+	}
+	for _, f := range oFunc {
+		f(options)
+	}
+	return options
+}
+
+// runSimpleConsumer 用已启动的官方客户端消费者创建简单消费者，并在后台运行接收循环
+func runSimpleConsumer(ctx context.Context, cfg *Config, options *ConsumerOptions, consumer rmq_client.SimpleConsumer, consumeFunc ConsumeFunc, batchConsumeFunc BatchConsumeFunc, releaseCredentials func()) (c *simpleConsumer) {
+	receiveCtx, cancelReceive := context.WithCancel(ctx)
+	c = &simpleConsumer{
+		ctx:             ctx,
+		cfg:             cfg,
+		options:         options,
+		consumer:        consumer,
+		consumeFunc:     consumeFunc,
+		batchFunc:       batchConsumeFunc,
+		stats:           newClientStats(healthKindConsumer),
+		stopCredentials: releaseCredentials,
+		receiveCtx:      receiveCtx,
+		cancelReceive:   cancelReceive,
+		taken:           make(chan struct{}, 1),
+		stopping:        make(chan struct{}),
+		loopDone:        make(chan struct{}),
+	}
+	c.queue = c.newMessageQueue()
+	if options.healthChecker != nil {
+		options.healthChecker.Register(options.healthName, statsReporter{stats: c.stats})
+	}
+	go c.run()
+	return
+}
+
+// simpleConsumer 运行中的简单消费者
+type simpleConsumer struct {
+	ctx             context.Context
+	cfg             *Config
+	options         *ConsumerOptions
+	consumer        rmq_client.SimpleConsumer
+	consumeFunc     ConsumeFunc
+	batchFunc       BatchConsumeFunc //不为nil时按批消费
+	stats           *clientStats
+	stopCredentials func()
+	receiveCtx      context.Context //停止接收时取消，用于中断等待中的Receive
+	cancelReceive   context.CancelFunc
+	queue           messageQueue  //预取的消息
+	taken           chan struct{} //消费协程从缓冲区取出消息时通知接收循环
+	poolMu          sync.Mutex
+	workerQuits     []chan struct{} //每个消费协程的退出信号
+	workers         sync.WaitGroup
+	poolClosed      bool
+	stopOnce        sync.Once
+	stopping        chan struct{} //关闭后不再接收新消息
+	loopDone        chan struct{} //接收循环退出后关闭
+	shutdownOnce    sync.Once
+	shutdownErr     error
+}
+
+// stopReceiving 停止接收新消息，正在执行的消费方法不受影响
+func (s *simpleConsumer) stopReceiving() {
+	s.stopOnce.Do(func() {
+		close(s.stopping)
+		s.cancelReceive()
+	})
+}
+
+// stop 停止接收新消息，等待正在执行的消费方法返回，最长等待StopTimeout，然后注销消费者
+func (s *simpleConsumer) stop() error {
+	s.stopReceiving()
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.StopTimeout)
+	defer cancel()
+	var waitErr error
+	if err := s.wait(ctx); err != nil {
+		waitErr = newError(CodeShutdownFailed, ErrMsgShutdownInFlight, s.stats.inFlight.Load()).wrap(err)
+		logEvent(s.ctx, s.cfg, slog.LevelError, opConsumerStop, "等待消费方法返回超时", attrError(waitErr))
+	}
+	return errors.Join(waitErr, s.shutdown())
+}
+
+// wait 等待接收循环退出，即正在执行的消费方法都已返回，ctx结束时返回ctx的错误
+func (s *simpleConsumer) wait(ctx context.Context) error {
+	select {
+	case <-s.loopDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown 注销官方客户端的消费者，需先停止接收，只会执行一次，重复调用返回第一次的结果
+func (s *simpleConsumer) shutdown() error {
+	s.shutdownOnce.Do(func() {
+		s.stats.started.Store(false)
+		s.stopCredentials()
+		s.shutdownErr = s.consumer.GracefulStop()
+		if s.shutdownErr != nil {
+			logEvent(s.ctx, s.cfg, slog.LevelError, opConsumerStop, "消费者注销失败", attrError(s.shutdownErr))
 			return
 		}
-		debugLog(cfg, "消费者注销成功")
+		logEvent(s.ctx, s.cfg, slog.LevelInfo, opConsumerStop, "消费者注销成功")
+	})
+	return s.shutdownErr
+}
+
+// isStopping 是否已停止接收或ctx已结束
+func (s *simpleConsumer) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	case <-s.ctx.Done():
+		return true
+	default:
+		return false
 	}
+}
 
-	go func() {
-		for {
-			mvs, err1 := consumer.Receive(ctx, options.MaxMessageNum, options.InvisibleDuration)
-			if err1 != nil {
-				if IsNoNewMessage(err1) {
-					//无新消息，暂停一会儿再获取
-					time.Sleep(time.Second)
-					continue
-				}
-				debugLog(cfg, "获取消息失败:%v", err1)
-			}
-			for _, mv := range mvs {
-				consumeFunc(ctx, mv, &defaultConsumer{
-					mv:       mv,
-					consumer: consumer,
-				})
+// run 运行接收循环，ctx结束导致的退出会自动注销消费者
+func (s *simpleConsumer) run() {
+	s.setConcurrency(s.options.Concurrency)
+	s.receiveLoop()
+	s.closePool()
+	close(s.loopDone)
+	if s.ctx.Err() != nil {
+		s.stopReceiving()
+		_ = s.shutdown()
+	}
+}
+
+// receiveLoop 接收循环，缓冲区能放下一批消息时才接收，收到的消息放入缓冲区由消费协程处理
+// 停止接收或ctx结束后退出，缓冲区中还没开始处理的消息等不可见时间过后重新投递
+func (s *simpleConsumer) receiveLoop() {
+	ctx, cfg := s.ctx, s.cfg
+	batch := s.options.MaxMessageNum
+	if int(batch) > s.options.PrefetchSize {
+		batch = int32(s.options.PrefetchSize)
+	}
+	var (
+		failures int
+		errLog   repeatLog
+	)
+	defer errLog.flush(ctx, cfg, opReceive)
+	for !s.isStopping() {
+		if !s.waitBuffer(int(batch)) {
+			return
+		}
+		mvs, err1 := s.consumer.Receive(s.receiveCtx, batch, s.options.InvisibleDuration)
+		if err1 != nil && s.isStopping() {
+			//停止导致的接收失败
+			return
+		}
+		if err1 != nil && !IsNoNewMessage(err1) {
+			s.stats.record(err1)
+		} else {
+			s.stats.record(nil)
+		}
+		if err1 != nil && !IsNoNewMessage(err1) {
+			//接收失败，按指数退避等待后重试
+			failures++
+			delay := s.options.PollPolicy.errorDelay(failures)
+			level := slog.LevelError
+			if ClassifyError(err1).Retryable() {
+				level = slog.LevelWarn
 			}
+			errLog.log(ctx, cfg, level, opReceive, "获取消息失败，稍后重试", err1, slog.Int("failures", failures), slog.Duration("delay", delay))
+			s.sleep(delay)
+			continue
 		}
-	}()
-	return
+		if failures > 0 {
+			errLog.flush(ctx, cfg, opReceive)
+			logEvent(ctx, cfg, slog.LevelInfo, opReceive, "获取消息已恢复", slog.Int("failures", failures))
+			failures = 0
+		}
+		if err1 != nil {
+			//无新消息，暂停一会儿再获取
+			s.sleep(s.options.PollPolicy.jitter(s.options.PollPolicy.IdleDelay))
+			continue
+		}
+		received := time.Now()
+		s.stats.inFlight.Add(int64(len(mvs)))
+		for _, mv := range mvs {
+			s.queue.push(queueItem{mv: mv, received: received})
+		}
+	}
 }
@@ -0,0 +1,118 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"time"
+)
+
+// AckMode 消息确认模式
+type AckMode string
+
+const (
+	AckManual AckMode = "MANUAL" //消费方法内调用consumer.Ack()确认，默认
+	AckAuto   AckMode = "AUTO"   //消费方法返回nil时自动确认，返回错误时按NackStrategy处理；消费方法内已确认的不会重复确认
+)
+
+// NackStrategy 自动确认模式下消费方法返回错误时的处理方式
+type NackStrategy string
+
+const (
+	NackExpire  NackStrategy = "EXPIRE"  //不做处理，等不可见时间过后重新投递，默认
+	NackBackoff NackStrategy = "BACKOFF" //按投递次数从NackBackoff中取下次投递的延迟，调用ChangeInvisibleDuration
+)
+
+// DefaultNackBackoff 默认的重新投递延迟，和4.x版本消费重试的延迟级别相同，投递次数超过长度时使用最后一个
+var DefaultNackBackoff = []time.Duration{
+	10 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute,
+	5 * time.Minute, 6 * time.Minute, 7 * time.Minute, 8 * time.Minute, 9 * time.Minute, 10 * time.Minute,
+	20 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour,
+}
+
+func isValidAckMode(mode AckMode) bool {
+	switch mode {
+	case AckManual, AckAuto:
+		return true
+	}
+	return false
+}
+
+func isValidNackStrategy(strategy NackStrategy) bool {
+	switch strategy {
+	case NackExpire, NackBackoff:
+		return true
+	}
+	return false
+}
+
+// settle 按消费方法的结果处理消息：成功时自动确认模式下确认；失败且消费方法内没有确认或调整不可见时间时，按重新投递策略调整不可见时间，
+// 重试次数用完、错误不可重试或达到死信转发的投递次数时转发死信（如有配置）并确认消息不再投递。返回nil表示消息已处理完，不会再投递；否则返回消费方法或确认的错误
+func (s *simpleConsumer) settle(ctx context.Context, mv *rmq_client.MessageView, consumer *defaultConsumer, err error) error {
+	if err == nil {
+		if s.options.AckMode != AckAuto || consumer.acked.Load() {
+			return nil
+		}
+		if ackErr := consumer.Ack(ctx); ackErr != nil {
+			logEvent(ctx, s.cfg, slog.LevelError, opAck, "消息自动确认失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(ackErr))
+			return ackErr
+		}
+		return nil
+	}
+	if consumer.acked.Load() {
+		return nil
+	}
+	if consumer.changed.Load() {
+		return err
+	}
+	if s.deadLetterDue(mv, err) {
+		return s.giveUp(ctx, mv, consumer, err)
+	}
+	policy := s.retryPolicy(mv.GetTopic(), messageTag(mv))
+	if policy == nil {
+		return err
+	}
+	attempt := mv.GetDeliveryAttempt()
+	delay, ok := policy.NextDelay(attempt)
+	if !ok {
+		return s.giveUp(ctx, mv, consumer, err)
+	}
+	if nackErr := consumer.ChangeInvisibleDuration(delay); nackErr != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opNack, "调整消息不可见时间失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(nackErr))
+		return err
+	}
+	logEvent(ctx, s.cfg, slog.LevelDebug, opNack, "消息将延迟重新投递", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()),
+		slog.Int("delivery_attempt", int(attempt)),
+		slog.Duration("delay", delay),
+	)
+	return err
+}
+
+// giveUp 重试次数用完或错误不可重试，配置了死信转发时先转发到死信主题，然后确认消息不再投递
+func (s *simpleConsumer) giveUp(ctx context.Context, mv *rmq_client.MessageView, consumer *defaultConsumer, err error) error {
+	if s.options.DeadLetter != nil {
+		if dlErr := s.forwardDeadLetter(ctx, mv, err); dlErr != nil {
+			return err
+		}
+	}
+	if ackErr := consumer.Ack(ctx); ackErr != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opAck, "重试次数已用完，确认消息失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(ackErr))
+		return err
+	}
+	if s.options.DeadLetter != nil {
+		return nil
+	}
+	logEvent(ctx, s.cfg, slog.LevelError, opNack, "重试次数已用完，消息不再投递", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()),
+		slog.Int("delivery_attempt", int(mv.GetDeliveryAttempt())),
+		attrError(err),
+	)
+	return nil
+}
+
+// messageTag 消息的tag，没有时为空
+func messageTag(mv *rmq_client.MessageView) string {
+	if tag := mv.GetTag(); tag != nil {
+		return *tag
+	}
+	return ""
+}
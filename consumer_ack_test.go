@@ -0,0 +1,84 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"hash/crc32"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestAttemptMessageView 构造已投递attempt次的测试消息
+func newTestAttemptMessageView(id string, attempt int32) *rmq_client.MessageView {
+	return fromProtobufMessageView(&v2.Message{
+		Topic: &v2.Resource{Name: "t"},
+		SystemProperties: &v2.SystemProperties{
+			MessageId:       id,
+			DeliveryAttempt: &attempt,
+			BodyDigest: &v2.Digest{
+				Type:     v2.DigestType_CRC32,
+				Checksum: strconv.FormatInt(int64(crc32.ChecksumIEEE(nil)), 16),
+			},
+		},
+	})
+}
+
+func TestSettle(t *testing.T) {
+	errConsume := errors.New("consume failed")
+	errAck := errors.New("ack failed")
+	backoff := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	cases := []struct {
+		name       string
+		ackMode    AckMode
+		strategy   NackStrategy
+		attempt    int32
+		consumeErr error
+		ackErr     error
+		wantErr    error
+		wantAcked  bool
+		wantDelay  []time.Duration
+	}{
+		{"success auto ack", AckAuto, NackExpire, 1, nil, nil, nil, true, nil},
+		{"success manual ack", AckManual, NackExpire, 1, nil, nil, nil, false, nil},
+		{"ack error", AckAuto, NackExpire, 1, nil, errAck, errAck, false, nil},
+		{"failure expire", AckAuto, NackExpire, 1, errConsume, nil, errConsume, false, nil},
+		{"failure backoff first attempt", AckAuto, NackBackoff, 1, errConsume, nil, errConsume, false, []time.Duration{time.Second}},
+		{"failure backoff second attempt", AckAuto, NackBackoff, 2, errConsume, nil, errConsume, false, []time.Duration{2 * time.Second}},
+		{"failure backoff clamped", AckAuto, NackBackoff, 5, errConsume, nil, errConsume, false, []time.Duration{3 * time.Second}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestSimpleConsumer(&ConsumerOptions{
+				AckMode:           c.ackMode,
+				NackStrategy:      c.strategy,
+				NackBackoff:       backoff,
+				InvisibleDuration: 10 * time.Second,
+			})
+			receiver := newFakeReceiver()
+			receiver.ackErr = c.ackErr
+			mv := newTestAttemptMessageView("m1", c.attempt)
+			consumer := newDefaultConsumer(mv, receiver, time.Now(), s.options.InvisibleDuration)
+
+			err := s.settle(context.Background(), mv, consumer, c.consumeErr)
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("err = %v, want %v", err, c.wantErr)
+			}
+			if acked := slices.Contains(receiver.ackedIds(), "m1"); acked != c.wantAcked {
+				t.Fatalf("acked = %v, want %v", acked, c.wantAcked)
+			}
+			if got := receiver.changed("m1"); !slices.Equal(got, c.wantDelay) {
+				t.Fatalf("ChangeInvisibleDuration = %v, want %v", got, c.wantDelay)
+			}
+			//调整过不可见时间时，截止时间为调整后的时间
+			if len(c.wantDelay) > 0 {
+				if remaining := time.Until(consumer.invisibleUntil()); remaining <= c.wantDelay[0]-time.Second/2 {
+					t.Fatalf("invisible for %v, want about %v", remaining, c.wantDelay[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"time"
+)
+
+// BatchConsumeFunc 按批消费的方法，返回和msgs一一对应的消费结果，nil为消费成功；开启去重时msgs不包含已消费成功的重复消息
+// 成功的消息会被确认，失败的消息按重新投递策略和死信转发逐条处理；返回nil切片表示全部成功，长度和msgs不同时按全部失败处理
+type BatchConsumeFunc func(ctx context.Context, msgs []*rmq_client.MessageView) []error
+
+// WithConsumerOptionBatch 按批消费时每批最多的消息数和凑批等待的最长时间
+// 消费协程取到第一条消息后，继续从缓冲区取消息，直到达到size条或等待超过window
+func WithConsumerOptionBatch(size int, window time.Duration) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.BatchSize = size
+		o.BatchWindow = window
+	}
+}
+
+// SimpleBatchConsume 按批消费，除消费方法外和SimpleConsume相同，固定为自动确认模式
+func SimpleBatchConsume(ctx context.Context, cfg *Config, batchConsumeFunc BatchConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	c, err := startConsumer(ctx, cfg, nil, batchConsumeFunc, oFunc...)
+	if err != nil {
+		return
+	}
+	stopFunc = c.Stop
+	return
+}
+
+// StartSimpleBatchConsumer 启动按批消费的简单消费者，和SimpleBatchConsume相同，返回可调整并发数的消费者
+func StartSimpleBatchConsumer(ctx context.Context, cfg *Config, batchConsumeFunc BatchConsumeFunc, oFunc ...ConsumerOptionFunc) (SimpleConsumer, error) {
+	c, err := startConsumer(ctx, cfg, nil, batchConsumeFunc, oFunc...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// gather 从第一条消息开始凑批，达到BatchSize条、等待超过BatchWindow或缓冲区关闭时返回
+func (s *simpleConsumer) gather(first queueItem) []queueItem {
+	batch := make([]queueItem, 1, s.options.BatchSize)
+	batch[0] = first
+	deadline := make(chan struct{})
+	if s.options.BatchWindow > 0 {
+		timer := time.AfterFunc(s.options.BatchWindow, func() {
+			close(deadline)
+		})
+		defer timer.Stop()
+	} else {
+		close(deadline)
+	}
+	for len(batch) < s.options.BatchSize {
+		item, ok := s.queue.pop(deadline)
+		if !ok {
+			break
+		}
+		s.notifyTaken()
+		batch = append(batch, item)
+	}
+	return batch
+}
+
+// consumeBatch 按批消费，返回每条消息不可见的截止时间和处理结果，已停止接收时不再消费，等不可见时间过后重新投递
+func (s *simpleConsumer) consumeBatch(items []queueItem) (invisibleUntil []time.Time, errs []error) {
+	defer func() {
+		for range items {
+			s.stats.end()
+		}
+	}()
+	invisibleUntil = make([]time.Time, len(items))
+	errs = make([]error, len(items))
+	if s.isStopping() {
+		for i := range errs {
+			errs[i] = errConsumeSkipped
+		}
+		return
+	}
+	//已消费成功的重复消息直接确认，不交给消费方法
+	var (
+		pending   []*rmq_client.MessageView
+		consumers []*defaultConsumer
+		received  []time.Time
+		index     []int
+	)
+	for i, item := range items {
+		mv := item.mv
+		consumer := newDefaultConsumer(mv, s.consumer, item.received, s.options.InvisibleDuration)
+		if skipped, ackErr := s.skipDuplicate(mv, consumer); skipped {
+			invisibleUntil[i], errs[i] = consumer.invisibleUntil(), ackErr
+			continue
+		}
+		pending = append(pending, mv)
+		consumers = append(consumers, consumer)
+		received = append(received, item.received)
+		index = append(index, i)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancelCause(s.ctx)
+	defer cancel(nil)
+	var stopLeases []func()
+	if s.options.LeaseRenewal {
+		for i, consumer := range consumers {
+			stopLeases = append(stopLeases, s.keepLease(ctx, cancel, consumer, received[i]))
+		}
+	}
+	results := s.batchFunc(ctx, pending)
+	for _, stop := range stopLeases {
+		stop()
+	}
+	if results != nil && len(results) != len(pending) {
+		err := newError(CodeInvalidArgument, ErrMsgBatchResultMismatch, len(results), len(pending))
+		logEvent(s.ctx, s.cfg, slog.LevelError, opConsume, "批量消费结果数量不正确", attrError(err))
+		results = make([]error, len(pending))
+		for i := range results {
+			results[i] = err
+		}
+	}
+	for i, mv := range pending {
+		var err error
+		if results != nil {
+			err = results[i]
+		}
+		if err != nil {
+			logEvent(s.ctx, s.cfg, slog.LevelWarn, opConsume, "消息消费失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(err))
+		}
+		s.commitDedup(mv, consumers[i], err)
+		errs[index[i]] = s.settle(s.ctx, mv, consumers[i], err)
+		invisibleUntil[index[i]] = consumers[i].invisibleUntil()
+	}
+	return
+}
@@ -0,0 +1,133 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestBatchConsumer(options *ConsumerOptions, batchFunc BatchConsumeFunc) (*simpleConsumer, *fakeReceiver) {
+	options.AckMode = AckAuto
+	if options.PrefetchSize == 0 {
+		options.PrefetchSize = 10
+	}
+	if options.InvisibleDuration == 0 {
+		options.InvisibleDuration = 10 * time.Second
+	}
+	s := newTestSimpleConsumer(options)
+	receiver := newFakeReceiver()
+	s.consumer = receiver
+	s.batchFunc = batchFunc
+	return s, receiver
+}
+
+func pushTestItems(q messageQueue, ids ...string) {
+	for _, id := range ids {
+		q.push(queueItem{mv: newTestMessageView("t", id, "", ""), received: time.Now()})
+	}
+}
+
+func batchIds(batch []queueItem) []string {
+	ids := make([]string, 0, len(batch))
+	for _, item := range batch {
+		ids = append(ids, item.mv.GetMessageId())
+	}
+	return ids
+}
+
+func TestGather(t *testing.T) {
+	//缓冲区中的消息足够时达到BatchSize立即返回，不等BatchWindow
+	s, _ := newTestBatchConsumer(&ConsumerOptions{BatchSize: 3, BatchWindow: time.Hour}, nil)
+	pushTestItems(s.queue, "m1", "m2", "m3", "m4")
+	first, _ := s.queue.pop(nil)
+	start := time.Now()
+	if got := batchIds(s.gather(first)); !slices.Equal(got, []string{"m1", "m2", "m3"}) {
+		t.Fatalf("batch = %v, want [m1 m2 m3]", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("gather took %v with a full batch", elapsed)
+	}
+
+	//消息不够时等到BatchWindow，期间到达的消息也放入同一批
+	s, _ = newTestBatchConsumer(&ConsumerOptions{BatchSize: 3, BatchWindow: 100 * time.Millisecond}, nil)
+	pushTestItems(s.queue, "m1")
+	first, _ = s.queue.pop(nil)
+	time.AfterFunc(20*time.Millisecond, func() {
+		pushTestItems(s.queue, "m2")
+	})
+	start = time.Now()
+	if got := batchIds(s.gather(first)); !slices.Equal(got, []string{"m1", "m2"}) {
+		t.Fatalf("batch = %v, want [m1 m2]", got)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("gather returned after %v, before BatchWindow", elapsed)
+	}
+
+	//不设置BatchWindow时只取缓冲区中已有的消息
+	s, _ = newTestBatchConsumer(&ConsumerOptions{BatchSize: 3}, nil)
+	pushTestItems(s.queue, "m1", "m2")
+	first, _ = s.queue.pop(nil)
+	if got := batchIds(s.gather(first)); !slices.Equal(got, []string{"m1", "m2"}) {
+		t.Fatalf("batch = %v, want [m1 m2]", got)
+	}
+}
+
+func TestConsumeBatchResultMismatch(t *testing.T) {
+	for _, n := range []int{2, 4} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			s, receiver := newTestBatchConsumer(&ConsumerOptions{BatchSize: 3, NackStrategy: NackExpire}, func(context.Context, []*rmq_client.MessageView) []error {
+				return make([]error, n)
+			})
+			pushTestItems(s.queue, "m1", "m2", "m3")
+			first, _ := s.queue.pop(nil)
+			_, errs := s.consumeBatch(s.gather(first))
+			if len(errs) != 3 {
+				t.Fatalf("errs = %v, want 3 results", errs)
+			}
+			for i, err := range errs {
+				if !errors.Is(err, newError(CodeInvalidArgument, ErrMsgBatchResultMismatch)) {
+					t.Errorf("errs[%d] = %v, want a batch result mismatch", i, err)
+				}
+			}
+			if acked := receiver.ackedIds(); len(acked) != 0 {
+				t.Fatalf("acked = %v, want none", acked)
+			}
+		})
+	}
+}
+
+func TestConsumeBatchSettleEach(t *testing.T) {
+	errConsume := errors.New("consume failed")
+	s, receiver := newTestBatchConsumer(&ConsumerOptions{
+		BatchSize:    3,
+		NackStrategy: NackBackoff,
+		NackBackoff:  []time.Duration{time.Minute},
+	}, func(context.Context, []*rmq_client.MessageView) []error {
+		return []error{nil, errConsume, nil}
+	})
+	pushTestItems(s.queue, "m1", "m2", "m3")
+	first, _ := s.queue.pop(nil)
+	invisibleUntil, errs := s.consumeBatch(s.gather(first))
+
+	if errs[0] != nil || !errors.Is(errs[1], errConsume) || errs[2] != nil {
+		t.Fatalf("errs = %v, want only m2 failed", errs)
+	}
+	if got := receiver.ackedIds(); !slices.Equal(got, []string{"m1", "m3"}) {
+		t.Fatalf("acked = %v, want [m1 m3]", got)
+	}
+	if got := receiver.changed("m2"); !slices.Equal(got, []time.Duration{time.Minute}) {
+		t.Fatalf("m2 ChangeInvisibleDuration = %v, want [1m]", got)
+	}
+	for _, id := range []string{"m1", "m3"} {
+		if got := receiver.changed(id); len(got) != 0 {
+			t.Errorf("%s ChangeInvisibleDuration = %v, want none", id, got)
+		}
+	}
+	if remaining := time.Until(invisibleUntil[1]); remaining < 50*time.Second {
+		t.Fatalf("m2 invisible for %v, want about the retry delay", remaining)
+	}
+}
@@ -0,0 +1,412 @@
+package rocketmq_client
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupStore 消费幂等的去重存储，记录已消费成功的消息
+type DedupStore interface {
+	Seen(ctx context.Context, key string) (bool, error) //key是否已消费成功且未过期
+	Commit(ctx context.Context, key string) error       //记录key已消费成功
+}
+
+// DedupKeyFunc 获取消息的去重key，返回空时不去重
+type DedupKeyFunc func(mv *rmq_client.MessageView) string
+
+// DedupByMessageId 按消息ID去重，默认
+func DedupByMessageId(mv *rmq_client.MessageView) string {
+	return mv.GetMessageId()
+}
+
+// DedupByKey 按消息的第一个key去重，用于生产者重试时消息ID不同的情况
+func DedupByKey(mv *rmq_client.MessageView) string {
+	if keys := mv.GetKeys(); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// DedupByProperty 按消息的属性去重
+func DedupByProperty(name string) DedupKeyFunc {
+	return func(mv *rmq_client.MessageView) string {
+		return mv.GetProperties()[name]
+	}
+}
+
+// Dedup 消费幂等配置
+type Dedup struct {
+	Store DedupStore   //去重存储
+	Key   DedupKeyFunc //去重key，默认DedupByMessageId
+}
+
+// WithConsumerOptionDedup 消费前按key检查去重存储，已消费成功的消息直接确认，不调用消费方法；
+// 消费方法成功后（自动确认模式下返回nil，手动确认模式下返回nil且已确认）才记录到存储，消费中崩溃的消息重新投递后仍会消费；
+// 存储读写失败时按未消费处理，保证至少消费一次。keyFunc为nil时按消息ID去重
+func WithConsumerOptionDedup(store DedupStore, keyFunc DedupKeyFunc) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		if keyFunc == nil {
+			keyFunc = DedupByMessageId
+		}
+		o.Dedup = &Dedup{Store: store, Key: keyFunc}
+	}
+}
+
+// dedupKey 消息的去重key，未开启去重时为空
+func (s *simpleConsumer) dedupKey(mv *rmq_client.MessageView) string {
+	if s.options.Dedup == nil {
+		return ""
+	}
+	return s.options.Dedup.Key(mv)
+}
+
+// skipDuplicate 消息已消费成功时确认消息并返回true，确认失败时返回确认的错误
+func (s *simpleConsumer) skipDuplicate(mv *rmq_client.MessageView, consumer *defaultConsumer) (bool, error) {
+	key := s.dedupKey(mv)
+	if key == "" {
+		return false, nil
+	}
+	seen, err := s.options.Dedup.Store.Seen(s.ctx, key)
+	if err != nil {
+		logEvent(s.ctx, s.cfg, slog.LevelWarn, opDedup, "查询去重存储失败，按未消费处理", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), slog.String("dedup_key", key), attrError(err))
+		return false, nil
+	}
+	if !seen {
+		return false, nil
+	}
+	initMetrics()
+	consumerDedupCounter.Add(s.ctx, 1, metric.WithAttributes(
+		attribute.String(MetricKeyConsumerGroup, s.cfg.ConsumerGroup),
+		attribute.String(MetricKeyTopic, mv.GetTopic()),
+	))
+	if err = consumer.Ack(s.ctx); err != nil {
+		logEvent(s.ctx, s.cfg, slog.LevelError, opDedup, "确认重复消息失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), slog.String("dedup_key", key), attrError(err))
+		return true, err
+	}
+	logEvent(s.ctx, s.cfg, slog.LevelDebug, opDedup, "重复消息已确认，不再消费", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), slog.String("dedup_key", key))
+	return true, nil
+}
+
+// commitDedup 消费成功时记录到去重存储，需在确认前调用，自动确认模式下确认失败重新投递时不会重复消费
+func (s *simpleConsumer) commitDedup(mv *rmq_client.MessageView, consumer *defaultConsumer, err error) {
+	if err != nil || (s.options.AckMode != AckAuto && !consumer.acked.Load()) {
+		return
+	}
+	key := s.dedupKey(mv)
+	if key == "" {
+		return
+	}
+	if commitErr := s.options.Dedup.Store.Commit(s.ctx, key); commitErr != nil {
+		logEvent(s.ctx, s.cfg, slog.LevelError, opDedup, "记录去重存储失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), slog.String("dedup_key", key), attrError(commitErr))
+	}
+}
+
+// NewMemoryDedupStore 内存去重存储，最多保存capacity个key，超过时淘汰最久未记录的，capacity不大于0时不限制；
+// ttl为记录的有效期，0为不过期。进程重启后记录丢失
+func NewMemoryDedupStore(capacity int, ttl time.Duration) DedupStore {
+	return newMemoryDedupStore(capacity, ttl)
+}
+
+func newMemoryDedupStore(capacity int, ttl time.Duration) *memoryDedupStore {
+	return &memoryDedupStore{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+type memoryDedupStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List //按记录时间排列，最近记录的在前
+	items    map[string]*list.Element
+}
+
+type dedupEntry struct {
+	key string
+	at  time.Time
+}
+
+func (s *memoryDedupStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if !ok {
+		return false, nil
+	}
+	if s.expired(e.Value.(*dedupEntry).at, time.Now()) {
+		s.order.Remove(e)
+		delete(s.items, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *memoryDedupStore) Commit(_ context.Context, key string) error {
+	s.add(key, time.Now())
+	return nil
+}
+
+// add 记录key，at为记录时间
+func (s *memoryDedupStore) add(key string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[key]; ok {
+		e.Value.(*dedupEntry).at = at
+		s.order.MoveToFront(e)
+	} else {
+		s.items[key] = s.order.PushFront(&dedupEntry{key: key, at: at})
+	}
+	//淘汰超出容量和过期的记录
+	for back := s.order.Back(); back != nil; back = s.order.Back() {
+		if !(s.capacity > 0 && s.order.Len() > s.capacity) && !s.expired(back.Value.(*dedupEntry).at, at) {
+			break
+		}
+		s.order.Remove(back)
+		delete(s.items, back.Value.(*dedupEntry).key)
+	}
+}
+
+func (s *memoryDedupStore) expired(at, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(at) >= s.ttl
+}
+
+// len 记录数，包括还没淘汰的过期记录
+func (s *memoryDedupStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// entries 未过期的记录，按记录时间从早到晚
+func (s *memoryDedupStore) entries() []dedupEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	entries := make([]dedupEntry, 0, s.order.Len())
+	for e := s.order.Back(); e != nil; e = e.Prev() {
+		if entry := e.Value.(*dedupEntry); !s.expired(entry.at, now) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// fileDedupCompactMin 文件去重存储的行数至少达到多少才在运行中压缩
+var fileDedupCompactMin = 10000
+
+// FileDedupStore 文件去重存储，记录追加写入文件，同时在内存中保存未过期的记录
+// 打开时加载文件并去掉过期的记录，运行中文件行数超过未过期记录数的2倍时重写文件；
+// 写入不调用fsync，进程崩溃不会丢失记录，机器掉电可能丢失最近的记录
+type FileDedupStore struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	lines int //文件中的行数
+	mem   *memoryDedupStore
+}
+
+// NewFileDedupStore 打开文件去重存储，文件不存在时创建，ttl为记录的有效期，0为不过期
+func NewFileDedupStore(path string, ttl time.Duration) (*FileDedupStore, error) {
+	mem := newMemoryDedupStore(0, ttl)
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			ts, quoted, ok := strings.Cut(scanner.Text(), " ")
+			if !ok {
+				continue
+			}
+			nano, err1 := strconv.ParseInt(ts, 10, 64)
+			key, err2 := strconv.Unquote(quoted)
+			if err1 != nil || err2 != nil {
+				//写入中断的行
+				continue
+			}
+			mem.add(key, time.Unix(0, nano))
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgDedupFileFailed, path).wrap(err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, newError(CodeInvalidConfig, ErrMsgDedupFileFailed, path).wrap(err)
+	}
+	s := &FileDedupStore{path: path, mem: mem}
+	if err := s.compact(); err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgDedupFileFailed, path).wrap(err)
+	}
+	return s, nil
+}
+
+// compact 用未过期的记录重写文件，需持有锁；重写的文件改名后继续用于追加，改名前失败时仍写原文件
+func (s *FileDedupStore) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	entries := s.mem.entries()
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(w, "%d %s\n", entry.at.UnixNano(), strconv.Quote(entry.key))
+	}
+	if err = w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+	s.file, s.lines = f, len(entries)
+	return nil
+}
+
+// needCompact 文件中的行数是否超过未过期记录数的2倍，需持有锁
+func (s *FileDedupStore) needCompact() bool {
+	return s.lines >= fileDedupCompactMin && s.lines > 2*s.mem.len()
+}
+
+func (s *FileDedupStore) Seen(ctx context.Context, key string) (bool, error) {
+	return s.mem.Seen(ctx, key)
+}
+
+func (s *FileDedupStore) Commit(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return newError(CodeInvalidArgument, ErrMsgDedupStoreClosed, s.path)
+	}
+	now := time.Now()
+	if _, err := fmt.Fprintf(s.file, "%d %s\n", now.UnixNano(), strconv.Quote(key)); err != nil {
+		return err
+	}
+	s.lines++
+	s.mem.add(key, now)
+	if s.needCompact() {
+		//压缩失败不影响已写入的记录，下次写入时重试
+		if err := s.compact(); err != nil {
+			return newError(CodeInvalidConfig, ErrMsgDedupCompactFailed, s.path).wrap(err)
+		}
+	}
+	return nil
+}
+
+// Close 关闭文件，之后Commit返回错误
+func (s *FileDedupStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// sqlIdentifierRegexp 表名只允许字母、数字、下划线和点
+var sqlIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLDedupStore database/sql去重存储，表结构：
+//
+//	CREATE TABLE rocketmq_dedup (dedup_key VARCHAR(255) PRIMARY KEY, committed_at BIGINT NOT NULL)
+//
+// committed_at为记录时间的毫秒时间戳，过期的记录可通过Purge删除
+type SQLDedupStore struct {
+	db          *sql.DB
+	table       string
+	ttl         time.Duration
+	placeholder func(n int) string
+}
+
+// NewSQLDedupStore 创建database/sql去重存储，table为表名，ttl为记录的有效期，0为不过期
+// dollar为true时使用$1形式的占位符（如PostgreSQL），否则使用?（如MySQL、SQLite）
+func NewSQLDedupStore(db *sql.DB, table string, ttl time.Duration, dollar bool) (*SQLDedupStore, error) {
+	if db == nil {
+		return nil, newError(CodeInvalidArgument, ErrMsgRequired, "db")
+	}
+	if !sqlIdentifierRegexp.MatchString(table) {
+		return nil, newError(CodeInvalidArgument, ErrMsgInvalidTableName, table)
+	}
+	s := &SQLDedupStore{db: db, table: table, ttl: ttl, placeholder: func(int) string { return "?" }}
+	if dollar {
+		s.placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+	}
+	return s, nil
+}
+
+func (s *SQLDedupStore) Seen(ctx context.Context, key string) (bool, error) {
+	var committedAt int64
+	err := s.db.QueryRowContext(ctx, "SELECT committed_at FROM "+s.table+" WHERE dedup_key = "+s.placeholder(1), key).Scan(&committedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.ttl <= 0 || time.Since(time.UnixMilli(committedAt)) < s.ttl, nil
+}
+
+// Commit 先更新已有的记录，没有时插入；并发提交同一key时插入会因主键冲突失败，此时记录已存在，按成功处理
+func (s *SQLDedupStore) Commit(ctx context.Context, key string) error {
+	now := time.Now().UnixMilli()
+	res, err := s.db.ExecContext(ctx, "UPDATE "+s.table+" SET committed_at = "+s.placeholder(1)+" WHERE dedup_key = "+s.placeholder(2), now, key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO "+s.table+" (dedup_key, committed_at) VALUES ("+s.placeholder(1)+", "+s.placeholder(2)+")", key, now)
+	if err == nil {
+		return nil
+	}
+	//各数据库主键冲突的错误不同，按记录是否存在判断；MySQL更新的值不变时RowsAffected也为0
+	if exists, existsErr := s.exists(ctx, key); existsErr == nil && exists {
+		return nil
+	}
+	return err
+}
+
+func (s *SQLDedupStore) exists(ctx context.Context, key string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM "+s.table+" WHERE dedup_key = "+s.placeholder(1), key).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Purge 删除过期的记录，ttl为0时不删除，返回删除的记录数
+func (s *SQLDedupStore) Purge(ctx context.Context) (int64, error) {
+	if s.ttl <= 0 {
+		return 0, nil
+	}
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE committed_at < "+s.placeholder(1), time.Now().Add(-s.ttl).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
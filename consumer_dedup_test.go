@@ -0,0 +1,245 @@
+package rocketmq_client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryDedupStore(2, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = s.Commit(ctx, key)
+	}
+	//超过容量时淘汰最久未记录的
+	for key, want := range map[string]bool{"a": false, "b": true, "c": true, "d": false} {
+		if seen, _ := s.Seen(ctx, key); seen != want {
+			t.Errorf("Seen(%s) = %v, want %v", key, seen, want)
+		}
+	}
+	//重新记录的key移到最前
+	_ = s.Commit(ctx, "b")
+	_ = s.Commit(ctx, "d")
+	if seen, _ := s.Seen(ctx, "c"); seen {
+		t.Error("c should be evicted after b was committed again")
+	}
+
+	s = newMemoryDedupStore(0, time.Minute)
+	s.add("old", time.Now().Add(-2*time.Minute))
+	s.add("new", time.Now())
+	if seen, _ := s.Seen(ctx, "old"); seen {
+		t.Error("expired key is seen")
+	}
+	if seen, _ := s.Seen(ctx, "new"); !seen {
+		t.Error("fresh key is not seen")
+	}
+	if entries := s.entries(); len(entries) != 1 || entries[0].key != "new" {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(b), "\n")
+}
+
+func TestFileDedupStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dedup.log")
+	s, err := NewFileDedupStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b with space", "a"} {
+		if err = s.Commit(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//写入中断的行和过期的记录在打开时丢弃
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	_, _ = f.WriteString("1 \"expired\"\n12345 \"broken")
+	_ = f.Close()
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Commit(ctx, "c"); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Fatalf("Commit after Close = %v", err)
+	}
+
+	s, err = NewFileDedupStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for key, want := range map[string]bool{"a": true, "b with space": true, "expired": false, "broken": false} {
+		if seen, _ := s.Seen(ctx, key); seen != want {
+			t.Errorf("Seen(%s) after reopen = %v, want %v", key, seen, want)
+		}
+	}
+	if n := countLines(t, path); n != 2 {
+		t.Fatalf("lines after reopen = %d, want 2", n)
+	}
+}
+
+func TestFileDedupStoreCompact(t *testing.T) {
+	defer func(min int) { fileDedupCompactMin = min }(fileDedupCompactMin)
+	fileDedupCompactMin = 10
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dedup.log")
+	s, err := NewFileDedupStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	//重复记录同一批key，运行中行数超过记录数的2倍后压缩
+	for i := 0; i < 100; i++ {
+		if err = s.Commit(ctx, []string{"a", "b", "c"}[i%3]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLines(t, path); n >= fileDedupCompactMin {
+		t.Fatalf("lines = %d, file was not compacted", n)
+	}
+	//压缩后继续追加到新文件
+	if err = s.Commit(ctx, "d"); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+	s, err = NewFileDedupStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if seen, _ := s.Seen(ctx, key); !seen {
+			t.Errorf("Seen(%s) after compaction = false", key)
+		}
+	}
+}
+
+// fakeDedupDB 按SQLDedupStore使用的语句模拟的数据库，beforeInsert用于模拟并发提交
+type fakeDedupDB struct {
+	mu           sync.Mutex
+	rows         map[string]int64
+	beforeInsert func(key string)
+	insertErr    error
+}
+
+func (db *fakeDedupDB) Connect(context.Context) (driver.Conn, error) { return fakeDedupConn{db}, nil }
+func (db *fakeDedupDB) Driver() driver.Driver                        { return nil }
+
+type fakeDedupConn struct{ db *fakeDedupDB }
+
+func (c fakeDedupConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeDedupConn) Close() error                        { return nil }
+func (c fakeDedupConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeDedupConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	db := c.db
+	switch {
+	case strings.HasPrefix(query, "UPDATE"):
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		key, at := args[1].Value.(string), args[0].Value.(int64)
+		//和MySQL一样，值不变时不计入影响的行数
+		if old, ok := db.rows[key]; ok && old != at {
+			db.rows[key] = at
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "INSERT"):
+		key := args[0].Value.(string)
+		if db.beforeInsert != nil {
+			db.beforeInsert(key)
+		}
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		if db.insertErr != nil {
+			return nil, db.insertErr
+		}
+		if _, ok := db.rows[key]; ok {
+			return nil, errors.New("UNIQUE constraint failed: dedup_key")
+		}
+		db.rows[key] = args[1].Value.(int64)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+func (c fakeDedupConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	db := c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	at, ok := db.rows[args[0].Value.(string)]
+	rows := &fakeDedupRows{}
+	if ok {
+		rows.values = [][]driver.Value{{at}}
+		if strings.HasPrefix(query, "SELECT 1 ") {
+			rows.values = [][]driver.Value{{int64(1)}}
+		}
+	}
+	return rows, nil
+}
+
+type fakeDedupRows struct{ values [][]driver.Value }
+
+func (r *fakeDedupRows) Columns() []string { return []string{"v"} }
+func (r *fakeDedupRows) Close() error      { return nil }
+func (r *fakeDedupRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLDedupStoreCommit(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeDedupDB{rows: map[string]int64{}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	s, err := NewSQLDedupStore(db, "rocketmq_dedup", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Commit(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if seen, _ := s.Seen(ctx, "a"); !seen {
+		t.Fatal("committed key is not seen")
+	}
+
+	//并发提交同一key，另一个提交先插入时主键冲突按成功处理
+	fake.beforeInsert = func(key string) {
+		fake.mu.Lock()
+		fake.rows[key] = time.Now().UnixMilli()
+		fake.mu.Unlock()
+	}
+	if err = s.Commit(ctx, "b"); err != nil {
+		t.Fatalf("Commit with concurrent insert = %v", err)
+	}
+
+	//记录不存在时返回插入的错误
+	fake.beforeInsert = nil
+	fake.insertErr = errors.New("disk full")
+	if err = s.Commit(ctx, "c"); err == nil || err.Error() != "disk full" {
+		t.Fatalf("Commit with insert error = %v", err)
+	}
+
+	if _, err = NewSQLDedupStore(db, "bad-table", 0, false); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Fatalf("bad table name = %v", err)
+	}
+}
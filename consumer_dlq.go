@@ -0,0 +1,205 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 客户端死信消息中记录失败信息的属性
+const (
+	DeadLetterPropertyOriginTopic        = "DLQ_ORIGIN_TOPIC"         //原主题
+	DeadLetterPropertyOriginMessageId    = "DLQ_ORIGIN_MESSAGE_ID"    //原消息ID
+	DeadLetterPropertyOriginMessageGroup = "DLQ_ORIGIN_MESSAGE_GROUP" //原消息组，没有时不设置
+	DeadLetterPropertyConsumerGroup      = "DLQ_CONSUMER_GROUP"       //消费失败的消费者分组
+	DeadLetterPropertyDeliveryAttempt    = "DLQ_DELIVERY_ATTEMPT"     //转发时的投递次数
+	DeadLetterPropertyLastError          = "DLQ_LAST_ERROR"           //最后一次消费失败的错误信息
+	DeadLetterPropertyTimestamp          = "DLQ_TIMESTAMP"            //转发的时间，RFC3339格式
+)
+
+// deadLetterErrorMaxLen 死信消息中错误信息的最大长度
+const deadLetterErrorMaxLen = 1024
+
+// nonRetryableError 不可重试的消费错误
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// NonRetryable 把消费方法的错误标记为不可重试：不再按重新投递策略重试，配置了死信转发时转发到死信主题，然后确认消息
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &nonRetryableError{err: err}
+}
+
+// IsNonRetryable 错误是否被标记为不可重试
+func IsNonRetryable(err error) bool {
+	var e *nonRetryableError
+	return errors.As(err, &e)
+}
+
+// DeadLetter 客户端死信转发配置
+type DeadLetter struct {
+	Producer    Producer //发送死信消息的生产者，需包含死信主题
+	Topic       string   //死信主题，按NORMAL类型发送
+	MaxAttempts int32    //投递次数达到后转发，0为只转发不可重试的错误和重新投递策略次数用完的消息
+}
+
+// WithConsumerOptionDeadLetter 消费失败的消息在投递maxAttempts次后、错误被标记为NonRetryable或重新投递策略次数用完时，
+// 带上失败信息（见DeadLetterProperty*）转发到死信主题，转发成功后确认原消息；转发失败时不确认，等重新投递后再次转发
+func WithConsumerOptionDeadLetter(producer Producer, topic string, maxAttempts int32) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.DeadLetter = &DeadLetter{Producer: producer, Topic: topic, MaxAttempts: maxAttempts}
+	}
+}
+
+// checkDeadLetter 校验死信转发配置
+func checkDeadLetter(dl *DeadLetter) error {
+	if dl == nil {
+		return nil
+	}
+	if dl.Producer == nil {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "DeadLetter.Producer")
+	}
+	if strings.TrimSpace(dl.Topic) == "" {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "DeadLetter.Topic")
+	}
+	if dl.MaxAttempts < 0 {
+		return newError(CodeInvalidConfig, ErrMsgNegative, "DeadLetter.MaxAttempts", dl.MaxAttempts)
+	}
+	return nil
+}
+
+// deadLetterDue 消息是否应直接转发到死信主题
+func (s *simpleConsumer) deadLetterDue(mv *rmq_client.MessageView, err error) bool {
+	if IsNonRetryable(err) {
+		return true
+	}
+	dl := s.options.DeadLetter
+	return dl != nil && dl.MaxAttempts > 0 && mv.GetDeliveryAttempt() >= dl.MaxAttempts
+}
+
+// forwardDeadLetter 把消费失败的消息转发到死信主题
+func (s *simpleConsumer) forwardDeadLetter(ctx context.Context, mv *rmq_client.MessageView, consumeErr error) error {
+	dl := s.options.DeadLetter
+	msg := deadLetterMessage(mv, dl.Topic, s.cfg.ConsumerGroup, consumeErr)
+	_, err := dl.Producer.Send(ctx, TopicNormal, msg)
+	if err != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opDeadLetter, "转发死信消息失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), slog.String("dead_letter_topic", dl.Topic), attrError(err))
+		return err
+	}
+	initMetrics()
+	consumerDeadLetterCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(MetricKeyConsumerGroup, s.cfg.ConsumerGroup),
+		attribute.String(MetricKeyTopic, mv.GetTopic()),
+	))
+	logEvent(ctx, s.cfg, slog.LevelWarn, opDeadLetter, "消息已转发到死信主题", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()),
+		slog.String("dead_letter_topic", dl.Topic),
+		slog.Int("delivery_attempt", int(mv.GetDeliveryAttempt())),
+		attrError(consumeErr),
+	)
+	return nil
+}
+
+// deadLetterMessage 构造死信消息，保留原消息的内容、tag、key和属性，消息组记录在属性中
+func deadLetterMessage(mv *rmq_client.MessageView, topic, consumerGroup string, consumeErr error) Message {
+	properties := maps.Clone(mv.GetProperties())
+	if properties == nil {
+		properties = map[string]string{}
+	}
+	errText := ""
+	if consumeErr != nil {
+		errText = truncateText(consumeErr.Error(), deadLetterErrorMaxLen)
+	}
+	properties[DeadLetterPropertyOriginTopic] = mv.GetTopic()
+	properties[DeadLetterPropertyOriginMessageId] = mv.GetMessageId()
+	if group := mv.GetMessageGroup(); group != nil && *group != "" {
+		properties[DeadLetterPropertyOriginMessageGroup] = *group
+	}
+	properties[DeadLetterPropertyConsumerGroup] = consumerGroup
+	properties[DeadLetterPropertyDeliveryAttempt] = strconv.Itoa(int(mv.GetDeliveryAttempt()))
+	properties[DeadLetterPropertyLastError] = errText
+	properties[DeadLetterPropertyTimestamp] = time.Now().Format(time.RFC3339)
+	return Message{
+		Body:       string(mv.GetBody()),
+		Topic:      topic,
+		Tag:        messageTag(mv),
+		Keys:       mv.GetKeys(),
+		Properties: properties,
+	}
+}
+
+// truncateText 截断到最多maxLen字节，不截断多字节字符
+func truncateText(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	i := maxLen
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i]
+}
+
+// ReplayDeadLetterFunc 返回把客户端死信消息发回原主题的消费方法，用于消费死信主题
+// 发回的消息去掉了死信属性，有原消息组时按FIFO类型发送，否则按NORMAL类型发送；发送成功后确认死信消息，
+// 不是客户端死信的消息返回不可重试的错误
+func ReplayDeadLetterFunc(producer Producer) ConsumeFunc {
+	return func(ctx context.Context, mv *rmq_client.MessageView, consumer Consumer) error {
+		msg, topicType, err := replayMessage(mv)
+		if err != nil {
+			return NonRetryable(err)
+		}
+		if _, err = producer.Send(ctx, topicType, msg); err != nil {
+			return err
+		}
+		return consumer.Ack(ctx)
+	}
+}
+
+// replayMessage 按死信消息还原原消息
+func replayMessage(mv *rmq_client.MessageView) (msg Message, topicType TopicType, err error) {
+	properties := maps.Clone(mv.GetProperties())
+	topic := properties[DeadLetterPropertyOriginTopic]
+	if topic == "" {
+		err = newError(CodeInvalidMessage, ErrMsgNotDeadLetter, mv.GetMessageId())
+		return
+	}
+	group := properties[DeadLetterPropertyOriginMessageGroup]
+	for _, k := range []string{
+		DeadLetterPropertyOriginTopic, DeadLetterPropertyOriginMessageId, DeadLetterPropertyOriginMessageGroup,
+		DeadLetterPropertyConsumerGroup, DeadLetterPropertyDeliveryAttempt, DeadLetterPropertyLastError, DeadLetterPropertyTimestamp,
+	} {
+		delete(properties, k)
+	}
+	topicType = TopicNormal
+	if group != "" {
+		topicType = TopicFIFO
+	}
+	msg = Message{
+		Body:         string(mv.GetBody()),
+		Topic:        topic,
+		Tag:          messageTag(mv),
+		MessageGroup: group,
+		Keys:         mv.GetKeys(),
+		Properties:   properties,
+	}
+	return
+}
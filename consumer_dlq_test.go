@@ -0,0 +1,51 @@
+package rocketmq_client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		s      string
+		maxLen int
+		want   string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"中文错误", 6, "中文"},
+		{"中文错误", 7, "中文"},
+		{"中文错误", 8, "中文"},
+		{"中文错误", 9, "中文错"},
+		{"a中", 2, "a"},
+		{"中", 0, ""},
+	}
+	for _, c := range cases {
+		if got := truncateText(c.s, c.maxLen); got != c.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", c.s, c.maxLen, got, c.want)
+		}
+	}
+}
+
+func TestDeadLetterMessage(t *testing.T) {
+	mv := newTestMessageView("t", "m1", "g1", "body")
+	//多字节字符跨越长度上限时不能截出不合法的UTF-8
+	consumeErr := errors.New("x" + strings.Repeat("错", deadLetterErrorMaxLen))
+	msg := deadLetterMessage(mv, "t_dlq", "cg", consumeErr)
+	lastErr := msg.Properties[DeadLetterPropertyLastError]
+	if len(lastErr) > deadLetterErrorMaxLen || len(lastErr) < deadLetterErrorMaxLen-utf8.UTFMax {
+		t.Fatalf("last error length = %d", len(lastErr))
+	}
+	if !utf8.ValidString(lastErr) {
+		t.Fatal("last error is not valid UTF-8")
+	}
+	if msg.Topic != "t_dlq" || msg.Body != "body" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if msg.Properties[DeadLetterPropertyOriginTopic] != "t" || msg.Properties[DeadLetterPropertyOriginMessageId] != "m1" ||
+		msg.Properties[DeadLetterPropertyOriginMessageGroup] != "g1" || msg.Properties[DeadLetterPropertyConsumerGroup] != "cg" {
+		t.Fatalf("unexpected properties %v", msg.Properties)
+	}
+}
@@ -0,0 +1,210 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+	"time"
+)
+
+// newMessageQueue 按消费者选项创建预取缓冲区，Fifo时按消息组调度
+func (s *simpleConsumer) newMessageQueue() messageQueue {
+	if !s.options.Fifo {
+		return newChanQueue(s.options.PrefetchSize)
+	}
+	initMetrics()
+	return &fifoQueue{
+		capacity:          s.options.PrefetchSize,
+		invisibleDuration: s.options.InvisibleDuration,
+		consumerGroup:     s.cfg.ConsumerGroup,
+		onSkip: func(mv *rmq_client.MessageView) {
+			s.stats.end()
+		},
+		onFree:  s.notifyTaken,
+		changed: make(chan struct{}),
+		groups:  map[string]*fifoGroup{},
+	}
+}
+
+// fifoQueue 按消息组调度的缓冲区：不同消息组的消息可以被不同的消费协程同时消费，同一消息组的消息按接收顺序逐条消费
+// 消息消费失败时，该消息组暂停推进：缓冲区中该组后续的消息不再消费，直到失败的消息重新投递或超过它的不可见时间（按重新投递策略调整过时为调整后的时间），
+// 期间收到的该组其他消息也不消费，等服务端按顺序重新投递；没有消息组的消息不保证顺序
+type fifoQueue struct {
+	capacity          int
+	invisibleDuration time.Duration
+	consumerGroup     string
+	onSkip            func(mv *rmq_client.MessageView) //跳过的消息不会再取出
+	onFree            func()                           //跳过缓冲区中的消息腾出空位时调用，用于唤醒等待空位的接收循环
+
+	mu        sync.Mutex
+	changed   chan struct{} //状态变化时关闭并替换，用于唤醒等待的消费协程
+	size      int           //缓冲区中还没取出的消息数
+	groups    map[string]*fifoGroup
+	ready     []*fifoGroup //有待消费的消息且没有消息在消费中的消息组，按就绪顺序
+	closed    bool
+	nextSweep time.Time
+}
+
+type fifoGroup struct {
+	key          string
+	topic        string
+	pending      []queueItem
+	busy         bool      //有消息在消费中
+	blockedBy    string    //消费失败、等待重新投递的消息ID
+	blockedUntil time.Time //失败的消息不可见的截止时间，超过后不再等待
+}
+
+// fifoGroupKey 消息组按主题区分，没有消息组的消息各自单独调度
+func fifoGroupKey(mv *rmq_client.MessageView) string {
+	group := mv.GetMessageGroup()
+	if group == nil || *group == "" {
+		return "\x00" + mv.GetMessageId()
+	}
+	return mv.GetTopic() + "\x00" + *group
+}
+
+func (q *fifoQueue) attrs(topic string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String(MetricKeyConsumerGroup, q.consumerGroup),
+		attribute.String(MetricKeyTopic, topic),
+	)
+}
+
+// broadcast 唤醒等待的消费协程，需持有锁
+func (q *fifoQueue) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *fifoQueue) push(item queueItem) {
+	mv := item.mv
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := fifoGroupKey(mv)
+	g, ok := q.groups[key]
+	if !ok {
+		g = &fifoGroup{key: key, topic: mv.GetTopic()}
+		q.groups[key] = g
+		consumerFifoGroupsCounter.Add(context.Background(), 1, q.attrs(g.topic))
+	}
+	if g.blockedBy != "" {
+		if mv.GetMessageId() != g.blockedBy && time.Now().Before(g.blockedUntil) {
+			q.skip(g, mv)
+			return
+		}
+		g.blockedBy, g.blockedUntil = "", time.Time{}
+	}
+	g.pending = append(g.pending, item)
+	q.size++
+	if !g.busy && len(g.pending) == 1 {
+		q.ready = append(q.ready, g)
+		q.broadcast()
+	}
+}
+
+func (q *fifoQueue) pop(quit <-chan struct{}) (queueItem, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.ready) > 0 {
+			g := q.ready[0]
+			q.ready = q.ready[1:]
+			item := g.pending[0]
+			g.pending = g.pending[1:]
+			g.busy = true
+			q.size--
+			q.mu.Unlock()
+			attrs := q.attrs(g.topic)
+			consumerFifoDispatchedCounter.Add(context.Background(), 1, attrs)
+			consumerFifoWaitHistogram.Record(context.Background(), time.Since(item.received).Seconds(), attrs)
+			return item, true
+		}
+		if q.closed && q.size == 0 {
+			q.mu.Unlock()
+			return queueItem{}, false
+		}
+		changed := q.changed
+		q.mu.Unlock()
+		select {
+		case <-quit:
+			return queueItem{}, false
+		case <-changed:
+		}
+	}
+}
+
+func (q *fifoQueue) done(mv *rmq_client.MessageView, err error, invisibleUntil time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	g, ok := q.groups[fifoGroupKey(mv)]
+	if !ok {
+		return
+	}
+	g.busy = false
+	if err != nil && err != errConsumeSkipped {
+		//消费失败，暂停推进，后续的消息等服务端按顺序重新投递
+		if invisibleUntil.IsZero() {
+			invisibleUntil = time.Now().Add(q.invisibleDuration)
+		}
+		g.blockedBy, g.blockedUntil = mv.GetMessageId(), invisibleUntil
+		for _, item := range g.pending {
+			q.size--
+			q.skip(g, item.mv)
+		}
+		if len(g.pending) > 0 {
+			//跳过的消息不会被取出，接收循环收不到取出的通知
+			q.onFree()
+		}
+		g.pending = nil
+	}
+	if len(g.pending) > 0 {
+		q.ready = append(q.ready, g)
+	}
+	q.release(g)
+	q.sweep()
+	q.broadcast()
+}
+
+// skip 跳过消息，需持有锁
+func (q *fifoQueue) skip(g *fifoGroup, mv *rmq_client.MessageView) {
+	consumerFifoBlockedCounter.Add(context.Background(), 1, q.attrs(g.topic))
+	q.onSkip(mv)
+}
+
+// release 消息组没有消息且没有暂停推进时移除，需持有锁
+func (q *fifoQueue) release(g *fifoGroup) {
+	if g.busy || len(g.pending) > 0 || g.blockedBy != "" {
+		return
+	}
+	delete(q.groups, g.key)
+	consumerFifoGroupsCounter.Add(context.Background(), -1, q.attrs(g.topic))
+}
+
+// sweep 定期移除暂停推进已超时且没有消息的消息组，失败的消息可能不再投递，如已被其他消费者消费，需持有锁
+func (q *fifoQueue) sweep() {
+	now := time.Now()
+	if now.Before(q.nextSweep) {
+		return
+	}
+	q.nextSweep = now.Add(q.invisibleDuration)
+	for _, g := range q.groups {
+		if g.blockedBy != "" && now.After(g.blockedUntil) {
+			g.blockedBy, g.blockedUntil = "", time.Time{}
+			q.release(g)
+		}
+	}
+}
+
+func (q *fifoQueue) free() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.capacity - q.size
+}
+
+func (q *fifoQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.broadcast()
+}
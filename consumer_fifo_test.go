@@ -0,0 +1,222 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"hash/crc32"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	_ "unsafe"
+)
+
+//go:linkname fromProtobufMessageView github.com/apache/rocketmq-clients/golang/v5.fromProtobuf_MessageView0
+func fromProtobufMessageView(message *v2.Message) *rmq_client.MessageView
+
+// newTestMessageView 构造测试用的消息，group为空时没有消息组
+func newTestMessageView(topic, id, group string, body string) *rmq_client.MessageView {
+	sp := &v2.SystemProperties{
+		MessageId: id,
+		BodyDigest: &v2.Digest{
+			Type:     v2.DigestType_CRC32,
+			Checksum: strconv.FormatInt(int64(crc32.ChecksumIEEE([]byte(body))), 16),
+		},
+	}
+	if group != "" {
+		sp.MessageGroup = &group
+	}
+	return fromProtobufMessageView(&v2.Message{
+		Topic:            &v2.Resource{Name: topic},
+		SystemProperties: sp,
+		Body:             []byte(body),
+	})
+}
+
+// newTestSimpleConsumer 构造不连接服务端的消费者，只用于测试缓冲区和消费流程
+func newTestSimpleConsumer(options *ConsumerOptions) *simpleConsumer {
+	ctx := context.Background()
+	receiveCtx, cancelReceive := context.WithCancel(ctx)
+	s := &simpleConsumer{
+		ctx:           ctx,
+		cfg:           &Config{ConsumerGroup: "cg_test"},
+		options:       options,
+		stats:         newClientStats(healthKindConsumer),
+		receiveCtx:    receiveCtx,
+		cancelReceive: cancelReceive,
+		taken:         make(chan struct{}, 1),
+		stopping:      make(chan struct{}),
+		loopDone:      make(chan struct{}),
+	}
+	s.queue = s.newMessageQueue()
+	return s
+}
+
+// popMessage 从缓冲区取出消息
+func popMessage(q messageQueue, quit <-chan struct{}) (*rmq_client.MessageView, bool) {
+	item, ok := q.pop(quit)
+	return item.mv, ok
+}
+
+func TestFifoQueueOrder(t *testing.T) {
+	s := newTestSimpleConsumer(&ConsumerOptions{Fifo: true, PrefetchSize: 10, InvisibleDuration: time.Minute})
+	q := s.queue
+	q.push(queueItem{mv: newTestMessageView("t", "a1", "a", ""), received: time.Now()})
+	q.push(queueItem{mv: newTestMessageView("t", "a2", "a", ""), received: time.Now()})
+	q.push(queueItem{mv: newTestMessageView("t", "b1", "b", ""), received: time.Now()})
+
+	//不同消息组可以同时取出，同一消息组要等前一条结束
+	mv1, _ := popMessage(q, nil)
+	mv2, _ := popMessage(q, nil)
+	if mv1.GetMessageId() != "a1" || mv2.GetMessageId() != "b1" {
+		t.Fatalf("pop = %s, %s, want a1, b1", mv1.GetMessageId(), mv2.GetMessageId())
+	}
+	quit := make(chan struct{})
+	close(quit)
+	if mv, ok := popMessage(q, quit); ok {
+		t.Fatalf("pop %s while group a is busy", mv.GetMessageId())
+	}
+	q.done(mv1, nil, time.Time{})
+	if mv, _ := popMessage(q, nil); mv.GetMessageId() != "a2" {
+		t.Fatalf("pop = %s, want a2", mv.GetMessageId())
+	}
+	if free := q.free(); free != 10 {
+		t.Fatalf("free = %d, want 10", free)
+	}
+}
+
+func TestFifoQueueDoneSkip(t *testing.T) {
+	s := newTestSimpleConsumer(&ConsumerOptions{Fifo: true, PrefetchSize: 10, InvisibleDuration: time.Minute})
+	q := s.queue
+	for i := 1; i <= 3; i++ {
+		s.stats.begin()
+		q.push(queueItem{mv: newTestMessageView("t", "a"+strconv.Itoa(i), "a", ""), received: time.Now()})
+	}
+	mv, _ := popMessage(q, nil)
+	s.stats.end()
+	q.done(mv, context.DeadlineExceeded, time.Time{})
+
+	//失败后该组缓冲区中的消息被跳过，空位恢复并通知接收循环
+	if free := q.free(); free != 10 {
+		t.Fatalf("free = %d, want 10", free)
+	}
+	if inFlight := s.stats.inFlight.Load(); inFlight != 0 {
+		t.Fatalf("inFlight = %d, want 0", inFlight)
+	}
+	select {
+	case <-s.taken:
+	default:
+		t.Fatal("skip did not notify the receive loop")
+	}
+
+	//失败的消息重新投递前，该组的其他消息也跳过
+	q.push(queueItem{mv: newTestMessageView("t", "a2", "a", ""), received: time.Now()})
+	if free := q.free(); free != 10 {
+		t.Fatalf("free = %d after blocked push, want 10", free)
+	}
+	q.push(queueItem{mv: newTestMessageView("t", "a1", "a", ""), received: time.Now()})
+	if mv, _ := popMessage(q, nil); mv.GetMessageId() != "a1" {
+		t.Fatalf("pop = %s, want redelivered a1", mv.GetMessageId())
+	}
+
+	//跳过的消息不计为失败，不暂停推进
+	q.push(queueItem{mv: newTestMessageView("t", "b1", "b", ""), received: time.Now()})
+	q.push(queueItem{mv: newTestMessageView("t", "b2", "b", ""), received: time.Now()})
+	mv, _ = popMessage(q, nil)
+	q.done(mv, errConsumeSkipped, time.Time{})
+	if mv, _ := popMessage(q, nil); mv.GetMessageId() != "b2" {
+		t.Fatalf("pop = %s, want b2", mv.GetMessageId())
+	}
+}
+
+// 缓冲区和每批接收数相同、一批消息都属于同一消息组时，第一条失败后接收循环要能继续接收
+func TestFifoQueueWaitBufferAfterFailure(t *testing.T) {
+	const size = 4
+	s := newTestSimpleConsumer(&ConsumerOptions{Fifo: true, PrefetchSize: size, MaxMessageNum: size, InvisibleDuration: time.Minute})
+	defer s.cancelReceive()
+	for i := 1; i <= size; i++ {
+		s.queue.push(queueItem{mv: newTestMessageView("t", "a"+strconv.Itoa(i), "a", ""), received: time.Now()})
+	}
+	mv, _ := popMessage(s.queue, nil)
+	s.notifyTaken()
+
+	waited := make(chan bool)
+	go func() {
+		waited <- s.waitBuffer(size)
+	}()
+	//等接收循环处理完取出的通知后再结束消费
+	for len(s.taken) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.queue.done(mv, context.DeadlineExceeded, time.Time{})
+	select {
+	case ok := <-waited:
+		if !ok {
+			t.Fatal("waitBuffer returned false")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waitBuffer did not wake up after the group was skipped")
+	}
+}
+
+// 重新投递的延迟比InvisibleDuration长时，消息组暂停推进到实际的重新投递时间
+func TestFifoBlockUntilRetryDelay(t *testing.T) {
+	receiver := newFakeReceiver()
+	var (
+		mu       sync.Mutex
+		consumed []string
+	)
+	startTestConsumer(t, context.Background(), receiver, func(_ context.Context, mv *rmq_client.MessageView, _ Consumer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		id := mv.GetMessageId()
+		first := !slices.Contains(consumed, id)
+		consumed = append(consumed, id)
+		if id == "a1" && first {
+			return errors.New("consume failed")
+		}
+		return nil
+	}, nil,
+		WithConsumerOptionFifo(true),
+		WithConsumerOptionInvisibleDuration(50*time.Millisecond),
+		WithConsumerOptionAckMode(AckAuto),
+		WithConsumerOptionNack(NackBackoff, time.Minute),
+	)
+	consumedIds := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(consumed)
+	}
+
+	receiver.send(newTestMessageView("t", "a1", "a", ""))
+	waitFor(t, "the retry delay of a1", func() bool {
+		return len(receiver.changed("a1")) == 1
+	})
+	if got := receiver.changed("a1"); got[0] != time.Minute {
+		t.Fatalf("retry delay = %v, want 1m", got[0])
+	}
+
+	//超过InvisibleDuration后，其他消息组的消费结束触发清理，a组仍暂停推进
+	time.Sleep(100 * time.Millisecond)
+	receiver.send(newTestMessageView("t", "b1", "b", ""))
+	waitFor(t, "b1 consumed", func() bool {
+		return slices.Contains(consumedIds(), "b1")
+	})
+	receiver.send(newTestMessageView("t", "a2", "a", ""))
+	time.Sleep(100 * time.Millisecond)
+	if got := consumedIds(); slices.Contains(got, "a2") {
+		t.Fatalf("consumed = %v, a2 consumed before a1 was redelivered", got)
+	}
+
+	//a1重新投递后恢复推进
+	receiver.send(newTestMessageView("t", "a1", "a", ""), newTestMessageView("t", "a2", "a", ""))
+	waitFor(t, "a2 consumed after a1", func() bool {
+		return slices.Contains(consumedIds(), "a2")
+	})
+	if got, want := consumedIds(), []string{"a1", "b1", "a1", "a2"}; !slices.Equal(got, want) {
+		t.Fatalf("consumed = %v, want %v", got, want)
+	}
+}
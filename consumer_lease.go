@@ -0,0 +1,90 @@
+package rocketmq_client
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithConsumerOptionLeaseRenewal 开启消费中消息不可见时间的自动续期：消费方法返回前，在不可见时间剩余不到2/3时续期InvisibleDuration；
+// maxProcessingTime为单条消息最长的消费时间，超过后不再续期并取消消费方法的ctx，0为不限制；
+// 续期失败时也会取消消费方法的ctx，context.Cause(ctx)为ErrLeaseLost
+func WithConsumerOptionLeaseRenewal(maxProcessingTime time.Duration) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.LeaseRenewal = true
+		o.MaxProcessingTime = maxProcessingTime
+	}
+}
+
+// keepLease 在后台为消费中的消息续期，续期失败或超过MaxProcessingTime时调用cancel取消消费方法的ctx，
+// 返回停止续期的方法，需在消费方法返回后、处理消费结果前调用；
+// 不可见时间从接收时开始计算，在缓冲区中等待过久、剩余不到2/3时开始消费后立即续期
+func (s *simpleConsumer) keepLease(ctx context.Context, cancel context.CancelCauseFunc, consumer *defaultConsumer, received time.Time) func() {
+	start := time.Now()
+	consumer.leaseUntil.Store(received.Add(s.options.InvisibleDuration).UnixNano())
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.renewLease(ctx, cancel, consumer, start, quit)
+	}()
+	return func() {
+		close(quit)
+		<-done
+	}
+}
+
+// renewLease 续期循环，quit关闭、消息已确认、续期失败或超过MaxProcessingTime时返回
+func (s *simpleConsumer) renewLease(ctx context.Context, cancel context.CancelCauseFunc, consumer *defaultConsumer, start time.Time, quit <-chan struct{}) {
+	mv := consumer.mv
+	margin := s.options.InvisibleDuration * 2 / 3
+	var deadline time.Time
+	if s.options.MaxProcessingTime > 0 {
+		deadline = start.Add(s.options.MaxProcessingTime)
+	}
+	for {
+		leaseUntil := time.Unix(0, consumer.leaseUntil.Load())
+		renewAt := leaseUntil.Add(-margin)
+		if !deadline.IsZero() && (renewAt.After(deadline) || !leaseUntil.Before(deadline)) {
+			renewAt = deadline
+		}
+		timer := time.NewTimer(time.Until(renewAt))
+		select {
+		case <-quit:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if consumer.acked.Load() {
+			return
+		}
+		now := time.Now()
+		if !deadline.IsZero() && !now.Before(deadline) {
+			err := newError(CodeLeaseLost, ErrMsgMaxProcessingTime, mv.GetMessageId(), s.options.MaxProcessingTime)
+			logEvent(ctx, s.cfg, slog.LevelWarn, opLease, "消息消费时间超过MaxProcessingTime，不再续期", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(err))
+			cancel(err)
+			return
+		}
+		//消费方法内调整过不可见时间时可能还不需要续期
+		if time.Unix(0, consumer.leaseUntil.Load()).Sub(now) > margin {
+			continue
+		}
+		extend := s.options.InvisibleDuration
+		if !deadline.IsZero() && deadline.Sub(now) < extend {
+			extend = deadline.Sub(now)
+		}
+		if renewErr := consumer.renew(extend); renewErr != nil {
+			err := newError(CodeLeaseLost, ErrMsgLeaseRenewFailed, mv.GetMessageId()).wrap(renewErr)
+			logEvent(ctx, s.cfg, slog.LevelError, opLease, "消息不可见时间续期失败，取消消费", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(err))
+			cancel(err)
+			return
+		}
+		logEvent(ctx, s.cfg, slog.LevelDebug, opLease, "消息不可见时间已续期", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()),
+			slog.Duration("extend", extend),
+			slog.Duration("elapsed", now.Sub(start)),
+		)
+	}
+}
@@ -0,0 +1,58 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSimpleConsumer 只记录调整不可见时间的次数，其他方法不可调用
+type fakeSimpleConsumer struct {
+	rmq_client.SimpleConsumer
+	renews atomic.Int32
+}
+
+func (c *fakeSimpleConsumer) ChangeInvisibleDuration(*rmq_client.MessageView, time.Duration) error {
+	c.renews.Add(1)
+	return nil
+}
+
+func TestKeepLeaseFromReceiveTime(t *testing.T) {
+	s := newTestSimpleConsumer(&ConsumerOptions{LeaseRenewal: true, InvisibleDuration: 3 * time.Second})
+	fake := &fakeSimpleConsumer{}
+	newConsumer := func() *defaultConsumer {
+		return &defaultConsumer{mv: newTestMessageView("t", "m1", "", ""), consumer: fake}
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	//刚接收的消息剩余的不可见时间足够，不续期
+	consumer := newConsumer()
+	stop := s.keepLease(ctx, cancel, consumer, time.Now())
+	time.Sleep(200 * time.Millisecond)
+	stop()
+	if n := fake.renews.Load(); n != 0 {
+		t.Fatalf("renews = %d for a fresh message, want 0", n)
+	}
+
+	//在缓冲区中等待过久的消息开始消费后立即续期
+	consumer = newConsumer()
+	received := time.Now().Add(-2500 * time.Millisecond)
+	stop = s.keepLease(ctx, cancel, consumer, received)
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for fake.renews.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	if n := fake.renews.Load(); n != 1 {
+		t.Fatalf("renews = %d for a message waiting in the buffer, want 1", n)
+	}
+	if leaseUntil := time.Unix(0, consumer.leaseUntil.Load()); time.Until(leaseUntil) < 2*time.Second {
+		t.Fatalf("lease was not extended, remaining %v", time.Until(leaseUntil))
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("consume ctx canceled: %v", context.Cause(ctx))
+	}
+}
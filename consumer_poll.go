@@ -0,0 +1,123 @@
+package rocketmq_client
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PollPolicy 接收消息的轮询策略：没有新消息时等待IdleDelay，接收失败时按指数退避加随机抖动等待，接收成功后重置
+type PollPolicy struct {
+	IdleDelay       time.Duration //没有新消息时再次接收前等待的时间，0为立即接收（服务端按AwaitDuration长轮询）
+	ErrorInitial    time.Duration //第一次接收失败后等待的时间
+	ErrorMax        time.Duration //接收失败后最长等待的时间
+	ErrorMultiplier float64       //连续失败时等待时间的倍数，不大于1时为2
+	Jitter          float64       //等待时间随机抖动的比例，0~1，如0.2为上下浮动20%
+}
+
+// DefaultPollPolicy 默认的轮询策略
+var DefaultPollPolicy = PollPolicy{
+	IdleDelay:       time.Second,
+	ErrorInitial:    200 * time.Millisecond,
+	ErrorMax:        30 * time.Second,
+	ErrorMultiplier: 2,
+	Jitter:          0.2,
+}
+
+// WithConsumerOptionPollPolicy 设置接收消息的轮询策略，默认DefaultPollPolicy
+func WithConsumerOptionPollPolicy(PollPolicy PollPolicy) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.PollPolicy = PollPolicy
+	}
+}
+
+// check 校验轮询策略
+func (p PollPolicy) check() error {
+	for _, v := range []struct {
+		key string
+		d   time.Duration
+	}{{"PollPolicy.IdleDelay", p.IdleDelay}, {"PollPolicy.ErrorInitial", p.ErrorInitial}, {"PollPolicy.ErrorMax", p.ErrorMax}} {
+		if v.d < 0 {
+			return newError(CodeInvalidConfig, ErrMsgNegative, v.key, v.d)
+		}
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return newError(CodeInvalidConfig, ErrMsgOutOfRange, "PollPolicy.Jitter", p.Jitter, 0, 1)
+	}
+	return nil
+}
+
+// errorDelay 第n次连续接收失败后等待的时间
+func (p PollPolicy) errorDelay(n int) time.Duration {
+	multiplier := p.ErrorMultiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	delay := float64(p.ErrorInitial) * math.Pow(multiplier, float64(n-1))
+	if p.ErrorMax > 0 && delay > float64(p.ErrorMax) {
+		delay = float64(p.ErrorMax)
+	}
+	//float64(math.MaxInt64)为2^63，转换为Duration会溢出为负数
+	if delay >= math.MaxInt64 {
+		return p.jitter(time.Duration(math.MaxInt64))
+	}
+	return p.jitter(time.Duration(delay))
+}
+
+// jitter 按Jitter随机浮动
+func (p PollPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	jittered := float64(d) * (1 + p.Jitter*(2*rand.Float64()-1))
+	if jittered >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(jittered)
+}
+
+// sleep 等待d，停止接收时提前返回
+func (s *simpleConsumer) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.receiveCtx.Done():
+	case <-timer.C:
+	}
+}
+
+// repeatLog 合并连续相同的错误日志：第一次出现时记录，之后相同的错误只计数，错误变化或恢复时记录一条带重复次数的日志
+type repeatLog struct {
+	last    string
+	level   slog.Level
+	repeats int
+	since   time.Time
+}
+
+// log 记录一次错误，和上一次相同时只计数
+func (l *repeatLog) log(ctx context.Context, cfg *Config, level slog.Level, op, msg string, err error, attrs ...slog.Attr) {
+	if text := err.Error(); text == l.last {
+		l.repeats++
+		return
+	}
+	l.flush(ctx, cfg, op)
+	l.last, l.level, l.since = err.Error(), level, time.Now()
+	logEvent(ctx, cfg, level, op, msg, append(attrs, attrError(err))...)
+}
+
+// flush 记录被合并的重复次数并重置
+func (l *repeatLog) flush(ctx context.Context, cfg *Config, op string) {
+	if l.repeats > 0 {
+		logEvent(ctx, cfg, l.level, op, "相同的错误重复出现",
+			slog.String(LogKeyError, l.last),
+			slog.Int("repeats", l.repeats),
+			slog.Duration("duration", time.Since(l.since)),
+		)
+	}
+	l.last, l.repeats = "", 0
+}
@@ -0,0 +1,96 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPollPolicyCheck(t *testing.T) {
+	if err := DefaultPollPolicy.check(); err != nil {
+		t.Fatalf("default policy: %v", err)
+	}
+	bad := []PollPolicy{
+		{IdleDelay: -1},
+		{ErrorInitial: -1},
+		{ErrorMax: -1},
+		{Jitter: -0.1},
+		{Jitter: 1.1},
+	}
+	for _, p := range bad {
+		if err := p.check(); ErrorCodeOf(err) != CodeInvalidConfig {
+			t.Errorf("check(%+v) = %v, want invalid config", p, err)
+		}
+	}
+}
+
+func TestPollPolicyErrorDelay(t *testing.T) {
+	p := PollPolicy{ErrorInitial: 100 * time.Millisecond, ErrorMax: time.Second, ErrorMultiplier: 3}
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second}
+	for i, d := range want {
+		if got := p.errorDelay(i + 1); got != d {
+			t.Errorf("errorDelay(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+	//ErrorMultiplier不大于1时为2，不限制最大值时不溢出
+	p = PollPolicy{ErrorInitial: time.Second}
+	if got := p.errorDelay(3); got != 4*time.Second {
+		t.Errorf("default multiplier errorDelay(3) = %v, want 4s", got)
+	}
+	if got := p.errorDelay(1000); got != time.Duration(math.MaxInt64) {
+		t.Errorf("errorDelay(1000) = %v, want max duration", got)
+	}
+
+	p = PollPolicy{ErrorInitial: time.Second, ErrorMax: time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := p.errorDelay(1); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jittered errorDelay = %v, want within 20%% of 1s", got)
+		}
+	}
+	if got := p.jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %v", got)
+	}
+	if got := (PollPolicy{ErrorInitial: time.Second, Jitter: 1}).errorDelay(1000); got <= 0 {
+		t.Errorf("jittered max errorDelay = %v, want positive", got)
+	}
+}
+
+func TestRepeatLog(t *testing.T) {
+	h := &recordHandler{}
+	cfg := &Config{Logger: slog.New(h)}
+	ctx := context.Background()
+	var l repeatLog
+	errA, errB := errors.New("a"), errors.New("b")
+	for i := 0; i < 3; i++ {
+		l.log(ctx, cfg, slog.LevelWarn, opReceive, "获取消息失败", errA)
+	}
+	l.log(ctx, cfg, slog.LevelWarn, opReceive, "获取消息失败", errB)
+	l.flush(ctx, cfg, opReceive)
+	//没有重复时flush不记录
+	l.flush(ctx, cfg, opReceive)
+
+	want := []string{"获取消息失败", "相同的错误重复出现", "获取消息失败"}
+	if got := h.messages(opReceive); !slices.Equal(got, want) {
+		t.Fatalf("messages = %v, want %v", got, want)
+	}
+	var repeats int64
+	h.records[1].Attrs(func(a slog.Attr) bool {
+		if a.Key == "repeats" {
+			repeats = a.Value.Int64()
+		}
+		return true
+	})
+	if repeats != 2 {
+		t.Fatalf("repeats = %d, want 2", repeats)
+	}
+
+	//flush后相同的错误重新记录
+	l.log(ctx, cfg, slog.LevelWarn, opReceive, "获取消息失败", errB)
+	if n := len(h.messages(opReceive)); n != 4 {
+		t.Fatalf("messages after flush = %d, want 4", n)
+	}
+}
@@ -0,0 +1,211 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"time"
+)
+
+// SimpleConsumer 运行中的简单消费者，可在运行中调整并发数
+type SimpleConsumer interface {
+	Stop() error                //停止接收新消息，等待正在执行的消费方法返回后注销，见SimpleConsume
+	SetConcurrency(n int) error //调整并发消费的协程数，n需大于0，减少时正在执行的消费方法不受影响
+	Concurrency() int           //当前并发消费的协程数
+	Health() ClientHealth       //健康状态
+}
+
+// StartSimpleConsumer 启动简单消费者，和SimpleConsume相同，返回可调整并发数的消费者
+func StartSimpleConsumer(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (SimpleConsumer, error) {
+	c, err := startSimpleConsumer(ctx, cfg, consumeFunc, oFunc...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *simpleConsumer) Stop() error {
+	return s.stop()
+}
+
+func (s *simpleConsumer) Health() ClientHealth {
+	return s.stats.health("")
+}
+
+func (s *simpleConsumer) Concurrency() int {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+	return len(s.workerQuits)
+}
+
+func (s *simpleConsumer) SetConcurrency(n int) error {
+	if n < 1 {
+		err := newError(CodeInvalidArgument, ErrMsgNonPositive, "concurrency", n)
+		logEvent(s.ctx, s.cfg, slog.LevelError, opConsume, "并发数不合法", attrError(err))
+		return err
+	}
+	s.setConcurrency(n)
+	logEvent(s.ctx, s.cfg, slog.LevelInfo, opConsume, "并发数已调整", slog.Int("concurrency", n))
+	return nil
+}
+
+// setConcurrency 增减消费协程，接收循环退出后不再启动新的协程
+func (s *simpleConsumer) setConcurrency(n int) {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+	if s.poolClosed {
+		return
+	}
+	for len(s.workerQuits) < n {
+		quit := make(chan struct{})
+		s.workerQuits = append(s.workerQuits, quit)
+		s.workers.Add(1)
+		go s.worker(quit)
+	}
+	for len(s.workerQuits) > n {
+		last := len(s.workerQuits) - 1
+		close(s.workerQuits[last])
+		s.workerQuits = s.workerQuits[:last]
+	}
+}
+
+// closePool 接收循环退出后关闭缓冲区，等待所有消费协程退出
+func (s *simpleConsumer) closePool() {
+	s.poolMu.Lock()
+	s.poolClosed = true
+	s.queue.close()
+	s.poolMu.Unlock()
+	s.workers.Wait()
+}
+
+// worker 消费协程，从缓冲区取消息消费，quit关闭或缓冲区关闭后退出
+func (s *simpleConsumer) worker(quit chan struct{}) {
+	defer s.workers.Done()
+	for {
+		//优先响应退出信号，避免并发数减少后继续取消息
+		select {
+		case <-quit:
+			return
+		default:
+		}
+		item, ok := s.queue.pop(quit)
+		if !ok {
+			return
+		}
+		s.notifyTaken()
+		if s.batchFunc != nil {
+			batch := s.gather(item)
+			invisibleUntil, errs := s.consumeBatch(batch)
+			for i, err := range errs {
+				s.queue.done(batch[i].mv, err, invisibleUntil[i])
+			}
+			continue
+		}
+		invisibleUntil, err := s.consume(item)
+		s.queue.done(item.mv, err, invisibleUntil)
+	}
+}
+
+// notifyTaken 通知接收循环缓冲区有空位
+func (s *simpleConsumer) notifyTaken() {
+	select {
+	case s.taken <- struct{}{}:
+	default:
+	}
+}
+
+// consume 消费一条消息，已停止接收时不再消费，等不可见时间过后重新投递
+// 返回消息不可见的截止时间和处理结果，失败时消息在截止时间后重新投递
+func (s *simpleConsumer) consume(item queueItem) (invisibleUntil time.Time, err error) {
+	defer s.stats.end()
+	mv := item.mv
+	if s.isStopping() {
+		return time.Time{}, errConsumeSkipped
+	}
+	consumer := newDefaultConsumer(mv, s.consumer, item.received, s.options.InvisibleDuration)
+	if skipped, ackErr := s.skipDuplicate(mv, consumer); skipped {
+		return consumer.invisibleUntil(), ackErr
+	}
+	if s.options.LeaseRenewal {
+		ctx, cancel := context.WithCancelCause(s.ctx)
+		stopLease := s.keepLease(ctx, cancel, consumer, item.received)
+		err = s.consumeFunc(ctx, mv, consumer)
+		stopLease()
+		cancel(nil)
+	} else {
+		err = s.consumeFunc(s.ctx, mv, consumer)
+	}
+	if err != nil {
+		logEvent(s.ctx, s.cfg, slog.LevelWarn, opConsume, "消息消费失败", attrTopic(mv.GetTopic()), attrMessageId(mv.GetMessageId()), attrError(err))
+	}
+	s.commitDedup(mv, consumer, err)
+	err = s.settle(s.ctx, mv, consumer, err)
+	return consumer.invisibleUntil(), err
+}
+
+// waitBuffer 等待缓冲区能放下n条消息，停止接收时返回false
+func (s *simpleConsumer) waitBuffer(n int) bool {
+	for s.queue.free() < n {
+		select {
+		case <-s.taken:
+		case <-s.receiveCtx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// errConsumeSkipped 停止接收后没有消费的消息
+var errConsumeSkipped = errors.New("consume skipped")
+
+// messageQueue 接收循环和消费协程之间的预取缓冲区，只有接收循环写入
+type messageQueue interface {
+	push(item queueItem)                                                  //放入消息，调用前需确认有空位
+	pop(quit <-chan struct{}) (item queueItem, ok bool)                   //取出可以消费的消息，没有时阻塞，quit关闭或缓冲区关闭且为空时ok为false
+	done(mv *rmq_client.MessageView, err error, invisibleUntil time.Time) //消息消费结束，err为处理结果，invisibleUntil为失败的消息重新投递的时间
+	free() int                                                            //空位数
+	close()                                                               //关闭，之后不再放入
+}
+
+// queueItem 缓冲区中的消息
+type queueItem struct {
+	mv       *rmq_client.MessageView
+	received time.Time //接收时间，消息的不可见时间从服务端返回时开始计算，在缓冲区中等待的时间也会消耗不可见时间
+}
+
+// chanQueue 按接收顺序取出的缓冲区
+type chanQueue chan queueItem
+
+func newChanQueue(size int) chanQueue {
+	return make(chanQueue, size)
+}
+
+func (q chanQueue) push(item queueItem) {
+	q <- item
+}
+
+func (q chanQueue) pop(quit <-chan struct{}) (queueItem, bool) {
+	//有消息时优先取出，quit已关闭时也不会漏取
+	select {
+	case item, ok := <-q:
+		return item, ok
+	default:
+	}
+	select {
+	case <-quit:
+		return queueItem{}, false
+	case item, ok := <-q:
+		return item, ok
+	}
+}
+
+func (q chanQueue) done(*rmq_client.MessageView, error, time.Time) {}
+
+func (q chanQueue) free() int {
+	return cap(q) - len(q)
+}
+
+func (q chanQueue) close() {
+	close(q)
+}
@@ -0,0 +1,100 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingHandler 消费方法阻塞到release关闭，记录同时执行的最大数量
+type blockingHandler struct {
+	release  chan struct{}
+	current  atomic.Int32
+	max      atomic.Int32
+	consumed atomic.Int32
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{release: make(chan struct{})}
+}
+
+func (h *blockingHandler) consume(context.Context, *rmq_client.MessageView, Consumer) error {
+	n := h.current.Add(1)
+	for {
+		m := h.max.Load()
+		if n <= m || h.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	<-h.release
+	h.current.Add(-1)
+	h.consumed.Add(1)
+	return nil
+}
+
+func testMessageViews(n int) []*rmq_client.MessageView {
+	mvs := make([]*rmq_client.MessageView, 0, n)
+	for i := 0; i < n; i++ {
+		mvs = append(mvs, newTestMessageView("t", "m"+strconv.Itoa(i), "", ""))
+	}
+	return mvs
+}
+
+func TestSimpleConsumerConcurrencyLimit(t *testing.T) {
+	receiver := newFakeReceiver()
+	receiver.send(testMessageViews(12)...)
+	h := newBlockingHandler()
+	startTestConsumer(t, context.Background(), receiver, h.consume, nil,
+		WithConsumerOptionConcurrency(3),
+		WithConsumerOptionAckMode(AckAuto),
+	)
+	waitFor(t, "3 running handlers", func() bool {
+		return h.current.Load() == 3
+	})
+	time.Sleep(50 * time.Millisecond)
+	if n := h.max.Load(); n != 3 {
+		t.Fatalf("max concurrent handlers = %d, want 3", n)
+	}
+
+	close(h.release)
+	waitFor(t, "all messages acked", func() bool {
+		return len(receiver.ackedIds()) == 12
+	})
+	if n := h.max.Load(); n > 3 {
+		t.Fatalf("max concurrent handlers = %d, want at most 3", n)
+	}
+}
+
+func TestSimpleConsumerPrefetchPause(t *testing.T) {
+	receiver := newFakeReceiver()
+	receiver.send(testMessageViews(10)...)
+	h := newBlockingHandler()
+	c := startTestConsumer(t, context.Background(), receiver, h.consume, nil,
+		WithConsumerOptionMaxMessageNum(2),
+		WithConsumerOptionPrefetchSize(3),
+		WithConsumerOptionAckMode(AckAuto),
+	)
+	//第一批2条，取出1条消费后缓冲区能放下第二批，之后缓冲区已满，暂停接收
+	waitFor(t, "the buffer to fill", func() bool {
+		return h.current.Load() == 1 && c.queue.free() == 0
+	})
+	time.Sleep(100 * time.Millisecond)
+	if n := receiver.receives.Load(); n != 2 {
+		t.Fatalf("receives = %d while the buffer is full, want 2", n)
+	}
+	if n := c.stats.inFlight.Load(); n != 4 {
+		t.Fatalf("in flight = %d, want 1 consuming and 3 buffered", n)
+	}
+
+	//消费方法返回后继续接收
+	close(h.release)
+	waitFor(t, "all messages acked", func() bool {
+		return len(receiver.ackedIds()) == 10
+	})
+	if n := h.max.Load(); n != 1 {
+		t.Fatalf("max concurrent handlers = %d, want 1", n)
+	}
+}
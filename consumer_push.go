@@ -0,0 +1,125 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"sync"
+)
+
+// ConsumeResult 推送消费的消费结果
+type ConsumeResult int
+
+const (
+	ConsumeSuccess ConsumeResult = iota //消费成功，确认消息
+	ConsumeFailure                      //消费失败，按重新投递策略延迟重新投递
+)
+
+// MessageListener 推送消费的监听方法，返回消费结果，panic时按ConsumeFailure处理
+type MessageListener func(ctx context.Context, msg *rmq_client.MessageView) ConsumeResult
+
+// PushConsumer 推送消费者，基于简单消费者实现，预取、并发、确认和重试都在内部处理
+type PushConsumer interface {
+	Start(ctx context.Context) error //启动消费者开始接收消息，ctx结束时自动注销，只能启动一次
+	Shutdown() error                 //停止接收新消息，等待正在执行的监听方法返回后注销，见SimpleConsume
+	Health() ClientHealth            //健康状态，未启动时Started为false
+}
+
+// WithConsumerOptionMaxCacheMessageCount 推送消费者本地缓存的消息数上限，同WithConsumerOptionPrefetchSize
+func WithConsumerOptionMaxCacheMessageCount(MaxCacheMessageCount int) ConsumerOptionFunc {
+	return WithConsumerOptionPrefetchSize(MaxCacheMessageCount)
+}
+
+// WithConsumerOptionConsumptionThreadCount 推送消费者的消费协程数，同WithConsumerOptionConcurrency
+func WithConsumerOptionConsumptionThreadCount(ConsumptionThreadCount int) ConsumerOptionFunc {
+	return WithConsumerOptionConcurrency(ConsumptionThreadCount)
+}
+
+// NewPushConsumer 创建推送消费者，需调用Start启动
+// 固定为自动确认模式：ConsumeSuccess时确认，ConsumeFailure时按重新投递策略处理，未设置策略时默认按DefaultNackBackoff延迟重新投递；
+// 用于FIFO主题时需设置WithConsumerOptionFifo(true)，同一消息组的消息按顺序逐条消费
+func NewPushConsumer(cfg *Config, listener MessageListener, oFunc ...ConsumerOptionFunc) (PushConsumer, error) {
+	return newPushConsumer(cfg, listener, StartSimpleConsumer, oFunc...)
+}
+
+// pushStartFunc 启动内部简单消费者的方法
+type pushStartFunc func(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (SimpleConsumer, error)
+
+func newPushConsumer(cfg *Config, listener MessageListener, start pushStartFunc, oFunc ...ConsumerOptionFunc) (PushConsumer, error) {
+	if listener == nil {
+		return nil, newError(CodeInvalidArgument, ErrMsgRequired, "listener")
+	}
+	oFuncs := append([]ConsumerOptionFunc{WithConsumerOptionNack(NackBackoff)}, oFunc...)
+	oFuncs = append(oFuncs, WithConsumerOptionAckMode(AckAuto))
+	return &pushConsumer{
+		cfg:      cfg,
+		listener: listener,
+		start:    start,
+		oFuncs:   oFuncs,
+	}, nil
+}
+
+type pushConsumer struct {
+	cfg      *Config
+	listener MessageListener
+	start    pushStartFunc
+	oFuncs   []ConsumerOptionFunc
+
+	mu       sync.Mutex
+	consumer SimpleConsumer
+	shutdown bool
+}
+
+func (s *pushConsumer) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return newError(CodeInvalidArgument, ErrMsgPushConsumerShutdown)
+	}
+	if s.consumer != nil {
+		return newError(CodeInvalidArgument, ErrMsgPushConsumerStarted)
+	}
+	consumer, err := s.start(ctx, s.cfg, s.consumeFunc, s.oFuncs...)
+	if err != nil {
+		return err
+	}
+	s.consumer = consumer
+	return nil
+}
+
+func (s *pushConsumer) Shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return nil
+	}
+	s.shutdown = true
+	if s.consumer == nil {
+		return nil
+	}
+	return s.consumer.Stop()
+}
+
+func (s *pushConsumer) Health() ClientHealth {
+	s.mu.Lock()
+	consumer := s.consumer
+	s.mu.Unlock()
+	if consumer == nil {
+		return ClientHealth{Kind: healthKindConsumer}
+	}
+	return consumer.Health()
+}
+
+// consumeFunc 把监听方法的结果转换为自动确认模式下的返回值
+func (s *pushConsumer) consumeFunc(ctx context.Context, msg *rmq_client.MessageView, _ Consumer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newError(CodeConsumeFailed, ErrMsgListenerPanic, msg.GetMessageId(), r)
+			logEvent(ctx, s.cfg, slog.LevelError, opConsume, "监听方法panic", attrTopic(msg.GetTopic()), attrMessageId(msg.GetMessageId()), attrError(err))
+		}
+	}()
+	if s.listener(ctx, msg) != ConsumeSuccess {
+		return newError(CodeConsumeFailed, ErrMsgConsumeFailure, msg.GetMessageId())
+	}
+	return nil
+}
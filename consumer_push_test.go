@@ -0,0 +1,172 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPushConsumerHealthNotStarted(t *testing.T) {
+	h := (&pushConsumer{}).Health()
+	if h.Kind != healthKindConsumer || h.Started {
+		t.Fatalf("health = %+v, want a consumer that is not started", h)
+	}
+}
+
+// newTestPushConsumer 用fakeReceiver启动内部的简单消费者，starts为启动的次数
+func newTestPushConsumer(t *testing.T, receiver *fakeReceiver, listener MessageListener, oFunc ...ConsumerOptionFunc) (PushConsumer, *atomic.Int32) {
+	t.Helper()
+	starts := &atomic.Int32{}
+	start := func(ctx context.Context, _ *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (SimpleConsumer, error) {
+		starts.Add(1)
+		return startTestConsumer(t, ctx, receiver, consumeFunc, nil, oFunc...), nil
+	}
+	p, err := newPushConsumer(&Config{ConsumerGroup: "cg_test"}, listener, start, oFunc...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, starts
+}
+
+func TestPushConsumerListenerResult(t *testing.T) {
+	receiver := newFakeReceiver()
+	p, _ := newTestPushConsumer(t, receiver, func(_ context.Context, msg *rmq_client.MessageView) ConsumeResult {
+		switch msg.GetMessageId() {
+		case "failure":
+			return ConsumeFailure
+		case "panic":
+			panic("listener panic")
+		}
+		return ConsumeSuccess
+	}, WithConsumerOptionConcurrency(3))
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	receiver.send(
+		newTestMessageView("t", "success", "", ""),
+		newTestMessageView("t", "failure", "", ""),
+		newTestMessageView("t", "panic", "", ""),
+	)
+	waitFor(t, "all messages settled", func() bool {
+		return len(receiver.ackedIds()) == 1 && len(receiver.changed("failure")) == 1 && len(receiver.changed("panic")) == 1
+	})
+
+	//成功的确认，失败和panic都按DefaultNackBackoff延迟重新投递
+	if got := receiver.ackedIds(); !slices.Equal(got, []string{"success"}) {
+		t.Fatalf("acked = %v, want [success]", got)
+	}
+	for _, id := range []string{"failure", "panic"} {
+		if got := receiver.changed(id); got[0] != DefaultNackBackoff[0] {
+			t.Errorf("%s retry delay = %v, want %v", id, got[0], DefaultNackBackoff[0])
+		}
+	}
+	if err := p.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushConsumerListenerPanic(t *testing.T) {
+	p, _ := newTestPushConsumer(t, newFakeReceiver(), func(context.Context, *rmq_client.MessageView) ConsumeResult {
+		panic("listener panic")
+	})
+	err := p.(*pushConsumer).consumeFunc(context.Background(), newTestMessageView("t", "m1", "", ""), nil)
+	if !errors.Is(err, newError(CodeConsumeFailed, ErrMsgListenerPanic)) {
+		t.Fatalf("err = %v, want a listener panic error", err)
+	}
+}
+
+func TestPushConsumerStartShutdown(t *testing.T) {
+	listener := func(context.Context, *rmq_client.MessageView) ConsumeResult {
+		return ConsumeSuccess
+	}
+	errStarted := newError(CodeInvalidArgument, ErrMsgPushConsumerStarted)
+	errShutdown := newError(CodeInvalidArgument, ErrMsgPushConsumerShutdown)
+
+	//重复启动返回错误，只启动一次
+	receiver := newFakeReceiver()
+	p, starts := newTestPushConsumer(t, receiver, listener)
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Health().Started {
+		t.Fatal("health not started after Start")
+	}
+	if err := p.Start(context.Background()); !errors.Is(err, errStarted) {
+		t.Fatalf("second start err = %v, want %v", err, errStarted)
+	}
+	if n := starts.Load(); n != 1 {
+		t.Fatalf("starts = %d, want 1", n)
+	}
+
+	//注销后不能再启动，重复注销返回nil
+	if err := p.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if !receiver.stopped.Load() || p.Health().Started {
+		t.Fatal("consumer not stopped after Shutdown")
+	}
+	if err := p.Shutdown(); err != nil {
+		t.Fatalf("second shutdown err = %v", err)
+	}
+	if err := p.Start(context.Background()); !errors.Is(err, errShutdown) {
+		t.Fatalf("start after shutdown err = %v, want %v", err, errShutdown)
+	}
+
+	//启动前注销，之后不能启动
+	p, starts = newTestPushConsumer(t, newFakeReceiver(), listener)
+	if err := p.Shutdown(); err != nil {
+		t.Fatalf("shutdown before start err = %v", err)
+	}
+	if err := p.Start(context.Background()); !errors.Is(err, errShutdown) {
+		t.Fatalf("start after shutdown err = %v, want %v", err, errShutdown)
+	}
+	if n := starts.Load(); n != 0 {
+		t.Fatalf("starts = %d, want 0", n)
+	}
+}
+
+func TestPushConsumerFifo(t *testing.T) {
+	receiver := newFakeReceiver()
+	var (
+		mu       sync.Mutex
+		consumed []string
+		running  atomic.Int32
+		overlap  atomic.Bool
+	)
+	p, _ := newTestPushConsumer(t, receiver, func(_ context.Context, msg *rmq_client.MessageView) ConsumeResult {
+		if running.Add(1) > 1 {
+			overlap.Store(true)
+		}
+		defer running.Add(-1)
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		consumed = append(consumed, msg.GetMessageId())
+		mu.Unlock()
+		return ConsumeSuccess
+	}, WithConsumerOptionFifo(true), WithConsumerOptionConsumptionThreadCount(4))
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a1", "a2", "a3", "a4", "a5"}
+	for _, id := range want {
+		receiver.send(newTestMessageView("t", id, "a", ""))
+	}
+	waitFor(t, "all messages acked", func() bool {
+		return len(receiver.ackedIds()) == len(want)
+	})
+
+	//同一消息组的消息按顺序逐条消费
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(consumed, want) {
+		t.Fatalf("consumed = %v, want %v", consumed, want)
+	}
+	if overlap.Load() {
+		t.Fatal("messages of the same group were consumed concurrently")
+	}
+}
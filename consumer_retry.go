@@ -0,0 +1,99 @@
+package rocketmq_client
+
+import (
+	"math"
+	"time"
+)
+
+// RetryPolicy 消费失败后的重新投递策略
+type RetryPolicy interface {
+	// NextDelay attempt为消息已投递的次数（MessageView.GetDeliveryAttempt()，第一次投递为1），返回下次投递的延迟，ok为false时不再重试
+	NextDelay(attempt int32) (delay time.Duration, ok bool)
+}
+
+// RetryPolicyFunc 自定义的重新投递策略
+type RetryPolicyFunc func(attempt int32) (delay time.Duration, ok bool)
+
+func (f RetryPolicyFunc) NextDelay(attempt int32) (time.Duration, bool) {
+	return f(attempt)
+}
+
+// NewFixedRetryPolicy 按投递次数从delays中取延迟，第1次投递失败取第1个，超过长度时使用最后一个
+// maxAttempts为最多投递的次数，达到后不再重试，0为不限制
+func NewFixedRetryPolicy(maxAttempts int32, delays ...time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int32) (time.Duration, bool) {
+		if exhausted(maxAttempts, attempt) || len(delays) == 0 {
+			return 0, false
+		}
+		i := int(attempt) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(delays) {
+			i = len(delays) - 1
+		}
+		return delays[i], true
+	})
+}
+
+// NewExponentialRetryPolicy 指数退避，第n次投递失败的延迟为initial*multiplier^(n-1)，最大为max（为0时不限制）
+// multiplier不大于1时为2；maxAttempts为最多投递的次数，达到后不再重试，0为不限制
+func NewExponentialRetryPolicy(maxAttempts int32, initial, max time.Duration, multiplier float64) RetryPolicy {
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	return RetryPolicyFunc(func(attempt int32) (time.Duration, bool) {
+		if exhausted(maxAttempts, attempt) {
+			return 0, false
+		}
+		if attempt < 1 {
+			attempt = 1
+		}
+		delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+		if max > 0 && delay > float64(max) {
+			return max, true
+		}
+		if delay >= math.MaxInt64 {
+			return time.Duration(math.MaxInt64), true
+		}
+		return time.Duration(delay), true
+	})
+}
+
+func exhausted(maxAttempts, attempt int32) bool {
+	return maxAttempts > 0 && attempt >= maxAttempts
+}
+
+// RetryRule 按主题和tag指定的重新投递策略
+type RetryRule struct {
+	Topic  string      //主题
+	Tag    string      //tag，为空或*时匹配主题的所有消息
+	Policy RetryPolicy //重新投递策略
+}
+
+// retryPolicy 获取消息的重新投递策略：tag匹配的规则、主题匹配的规则、RetryPolicy，都没有时自动确认模式下NackStrategy为NackBackoff时按NackBackoff
+func (s *simpleConsumer) retryPolicy(topic, tag string) RetryPolicy {
+	var topicPolicy RetryPolicy
+	for _, rule := range s.options.RetryRules {
+		if rule.Topic != topic {
+			continue
+		}
+		if rule.Tag == "" || rule.Tag == "*" {
+			if topicPolicy == nil {
+				topicPolicy = rule.Policy
+			}
+		} else if rule.Tag == tag {
+			return rule.Policy
+		}
+	}
+	if topicPolicy != nil {
+		return topicPolicy
+	}
+	if s.options.RetryPolicy != nil {
+		return s.options.RetryPolicy
+	}
+	if s.options.AckMode == AckAuto && s.options.NackStrategy == NackBackoff {
+		return NewFixedRetryPolicy(0, s.options.NackBackoff...)
+	}
+	return nil
+}
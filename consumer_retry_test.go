@@ -0,0 +1,111 @@
+package rocketmq_client
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFixedRetryPolicy(t *testing.T) {
+	p := NewFixedRetryPolicy(4, time.Second, 2*time.Second)
+	cases := []struct {
+		attempt int32
+		delay   time.Duration
+		ok      bool
+	}{
+		{0, time.Second, true},
+		{1, time.Second, true},
+		{2, 2 * time.Second, true},
+		{3, 2 * time.Second, true},
+		{4, 0, false},
+		{5, 0, false},
+	}
+	for _, c := range cases {
+		delay, ok := p.NextDelay(c.attempt)
+		if delay != c.delay || ok != c.ok {
+			t.Errorf("NextDelay(%d) = %v, %v, want %v, %v", c.attempt, delay, ok, c.delay, c.ok)
+		}
+	}
+	if _, ok := NewFixedRetryPolicy(0).NextDelay(1); ok {
+		t.Error("policy without delays should not retry")
+	}
+	if delay, ok := NewFixedRetryPolicy(0, time.Second).NextDelay(1000); !ok || delay != time.Second {
+		t.Errorf("unlimited policy NextDelay(1000) = %v, %v", delay, ok)
+	}
+}
+
+func TestExponentialRetryPolicy(t *testing.T) {
+	p := NewExponentialRetryPolicy(5, time.Second, 5*time.Second, 2)
+	cases := []struct {
+		attempt int32
+		delay   time.Duration
+		ok      bool
+	}{
+		{0, time.Second, true},
+		{1, time.Second, true},
+		{2, 2 * time.Second, true},
+		{3, 4 * time.Second, true},
+		{4, 5 * time.Second, true},
+		{5, 0, false},
+	}
+	for _, c := range cases {
+		delay, ok := p.NextDelay(c.attempt)
+		if delay != c.delay || ok != c.ok {
+			t.Errorf("NextDelay(%d) = %v, %v, want %v, %v", c.attempt, delay, ok, c.delay, c.ok)
+		}
+	}
+
+	//multiplier不大于1时为2
+	if delay, _ := NewExponentialRetryPolicy(0, time.Second, 0, 1).NextDelay(3); delay != 4*time.Second {
+		t.Errorf("default multiplier delay = %v, want 4s", delay)
+	}
+	//不限制最大值时不溢出，2^63转换为Duration也会溢出
+	if delay, ok := NewExponentialRetryPolicy(0, 1<<62, 0, 2).NextDelay(2); !ok || delay != time.Duration(math.MaxInt64) {
+		t.Errorf("2^63 delay = %v, %v", delay, ok)
+	}
+	if delay, ok := NewExponentialRetryPolicy(0, time.Second, 0, 10).NextDelay(100); !ok || delay != time.Duration(math.MaxInt64) {
+		t.Errorf("overflow delay = %v, %v", delay, ok)
+	}
+}
+
+func TestRetryPolicyRules(t *testing.T) {
+	fixed := func(d time.Duration) RetryPolicy {
+		return NewFixedRetryPolicy(0, d)
+	}
+	s := &simpleConsumer{options: &ConsumerOptions{
+		RetryRules: []RetryRule{
+			{Topic: "t1", Tag: "*", Policy: fixed(1 * time.Second)},
+			{Topic: "t1", Tag: "a", Policy: fixed(2 * time.Second)},
+			{Topic: "t1", Policy: fixed(3 * time.Second)},
+			{Topic: "t2", Tag: "b", Policy: fixed(4 * time.Second)},
+		},
+		RetryPolicy: fixed(5 * time.Second),
+	}}
+	cases := []struct {
+		topic, tag string
+		delay      time.Duration
+	}{
+		{"t1", "a", 2 * time.Second},
+		{"t1", "x", 1 * time.Second},
+		{"t1", "", 1 * time.Second},
+		{"t2", "b", 4 * time.Second},
+		{"t2", "x", 5 * time.Second},
+		{"t3", "a", 5 * time.Second},
+	}
+	for _, c := range cases {
+		delay, _ := s.retryPolicy(c.topic, c.tag).NextDelay(1)
+		if delay != c.delay {
+			t.Errorf("retryPolicy(%s, %s) delay = %v, want %v", c.topic, c.tag, delay, c.delay)
+		}
+	}
+
+	//没有规则和RetryPolicy时，自动确认且按NackBackoff时使用NackBackoff
+	s.options = &ConsumerOptions{AckMode: AckAuto, NackStrategy: NackBackoff, NackBackoff: []time.Duration{time.Second, time.Minute}}
+	if delay, _ := s.retryPolicy("t", "").NextDelay(2); delay != time.Minute {
+		t.Errorf("nack backoff delay = %v, want 1m", delay)
+	}
+	s.options = &ConsumerOptions{}
+	if p := s.retryPolicy("t", ""); p != nil {
+		t.Errorf("retryPolicy without any setting = %v, want nil", p)
+	}
+}
@@ -0,0 +1,216 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FallbackPolicy 路由没有匹配且没有设置兜底消费方法时的处理方式
+type FallbackPolicy string
+
+const (
+	FallbackAck        FallbackPolicy = "ACK"         //确认消息，不再投递
+	FallbackNack       FallbackPolicy = "NACK"        //返回错误，按重新投递策略重新投递
+	FallbackDeadLetter FallbackPolicy = "DEAD_LETTER" //返回不可重试的错误，配置了死信转发时转发到死信主题，否则确认，默认
+)
+
+// Router 按主题、tag和属性把消息分发到不同的消费方法，按注册顺序匹配，第一个匹配的路由处理消息
+// 路由需在启动消费者前注册；通过WithConsumerOptionRouter设置到消费者时，订阅表达式按注册的路由生成
+type Router struct {
+	mu       sync.RWMutex
+	routes   []*Route
+	fallback ConsumeFunc
+	policy   FallbackPolicy
+	err      error //注册路由时的第一个错误，启动消费者时返回
+}
+
+// Route 一条路由
+type Route struct {
+	topic      string
+	tags       []string          //tag模式，为空时匹配所有tag，支持path.Match的通配符
+	properties map[string]string //需要匹配的属性
+	handler    ConsumeFunc
+}
+
+// NewRouter 创建路由
+func NewRouter() *Router {
+	return &Router{policy: FallbackDeadLetter}
+}
+
+// Handle 注册路由，tagPattern为*或空时匹配主题的所有消息，多个tag用||分隔，tag支持*、?通配符，如order_*
+// 返回的Route可以通过WithProperty追加属性匹配条件
+func (r *Router) Handle(topic, tagPattern string, handler ConsumeFunc) *Route {
+	route := &Route{topic: strings.TrimSpace(topic), handler: handler}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case route.topic == "":
+		r.setErr(newError(CodeInvalidConfig, ErrMsgRequired, "Router.Handle.topic"))
+	case handler == nil:
+		r.setErr(newError(CodeInvalidConfig, ErrMsgRequired, "Router.Handle.handler"))
+	}
+	if pattern := strings.TrimSpace(tagPattern); pattern != "" && pattern != "*" {
+		for _, tag := range strings.Split(pattern, "||") {
+			tag = strings.TrimSpace(tag)
+			if _, err := path.Match(tag, ""); tag == "" || err != nil {
+				r.setErr(newError(CodeInvalidConfig, ErrMsgInvalidFilterExpression, route.topic, "TAG", tagPattern, "invalid tag pattern"))
+				continue
+			}
+			route.tags = append(route.tags, tag)
+		}
+	}
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// WithProperty 追加属性匹配条件，消息的属性key等于value时才匹配，可多次调用
+// 属性只在客户端匹配，不影响订阅表达式
+func (rt *Route) WithProperty(key, value string) *Route {
+	if rt.properties == nil {
+		rt.properties = map[string]string{}
+	}
+	rt.properties[key] = value
+	return rt
+}
+
+// SetFallback 设置没有匹配的路由时的兜底消费方法，设置后不再按FallbackPolicy处理
+func (r *Router) SetFallback(handler ConsumeFunc) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = handler
+	return r
+}
+
+// SetFallbackPolicy 设置没有匹配的路由且没有兜底消费方法时的处理方式，默认FallbackDeadLetter
+func (r *Router) SetFallbackPolicy(policy FallbackPolicy) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch policy {
+	case FallbackAck, FallbackNack, FallbackDeadLetter:
+		r.policy = policy
+	default:
+		r.setErr(newError(CodeInvalidConfig, ErrMsgInvalidFallbackPolicy, "Router.SetFallbackPolicy", policy))
+	}
+	return r
+}
+
+func (r *Router) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// check 返回注册路由时的错误
+func (r *Router) check() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.routes) == 0 {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "Router.routes")
+	}
+	return nil
+}
+
+// SubExpressions 按注册的路由生成订阅表达式：主题下有匹配所有tag或带通配符的路由时为*，否则为所有tag用||连接
+func (r *Router) SubExpressions() map[string]*FilterExpression {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tags := map[string]map[string]bool{}
+	all := map[string]bool{}
+	for _, route := range r.routes {
+		if tags[route.topic] == nil {
+			tags[route.topic] = map[string]bool{}
+		}
+		if len(route.tags) == 0 {
+			all[route.topic] = true
+		}
+		for _, tag := range route.tags {
+			if strings.ContainsAny(tag, `*?[\`) {
+				all[route.topic] = true
+			}
+			tags[route.topic][tag] = true
+		}
+	}
+	subExpressions := make(map[string]*FilterExpression, len(tags))
+	for topic, set := range tags {
+		if all[topic] {
+			subExpressions[topic] = SUB_ALL
+			continue
+		}
+		list := make([]string, 0, len(set))
+		for tag := range set {
+			list = append(list, tag)
+		}
+		sort.Strings(list)
+		subExpressions[topic] = NewFilterExpression(strings.Join(list, "||"))
+	}
+	return subExpressions
+}
+
+// Consume 按路由分发消息，可作为ConsumeFunc使用
+func (r *Router) Consume(ctx context.Context, msg *rmq_client.MessageView, consumer Consumer) error {
+	r.mu.RLock()
+	route := r.match(msg)
+	fallback, policy := r.fallback, r.policy
+	r.mu.RUnlock()
+	if route != nil {
+		return route.handler(ctx, msg, consumer)
+	}
+	if fallback != nil {
+		return fallback(ctx, msg, consumer)
+	}
+	err := newError(CodeNoRoute, ErrMsgNoRoute, msg.GetMessageId(), msg.GetTopic(), messageTag(msg))
+	switch policy {
+	case FallbackAck:
+		return consumer.Ack(ctx)
+	case FallbackNack:
+		return err
+	default:
+		return NonRetryable(err)
+	}
+}
+
+// match 查找第一个匹配的路由
+func (r *Router) match(msg *rmq_client.MessageView) *Route {
+	tag := messageTag(msg)
+	for _, route := range r.routes {
+		if route.topic == msg.GetTopic() && route.matchTag(tag) && route.matchProperties(msg.GetProperties()) {
+			return route
+		}
+	}
+	return nil
+}
+
+func (rt *Route) matchTag(tag string) bool {
+	if len(rt.tags) == 0 {
+		return true
+	}
+	for _, pattern := range rt.tags {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *Route) matchProperties(properties map[string]string) bool {
+	for k, v := range rt.properties {
+		if pv, ok := properties[k]; !ok || pv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// WithConsumerOptionRouter 按路由生成订阅表达式（已设置SubExpressions时不覆盖），启动时校验注册的路由，消费方法需传router.Consume
+func WithConsumerOptionRouter(router *Router) ConsumerOptionFunc {
+	return func(o *ConsumerOptions) {
+		o.router = router
+	}
+}
@@ -0,0 +1,139 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"testing"
+	"time"
+)
+
+// newTestTaggedMessage 构造带tag和属性的测试消息
+func newTestTaggedMessage(topic, tag string, properties map[string]string) *rmq_client.MessageView {
+	sp := &v2.SystemProperties{MessageId: "m1", BodyDigest: &v2.Digest{Type: v2.DigestType_CRC32, Checksum: "0"}}
+	if tag != "" {
+		sp.Tag = &tag
+	}
+	return fromProtobufMessageView(&v2.Message{
+		Topic:            &v2.Resource{Name: topic},
+		SystemProperties: sp,
+		UserProperties:   properties,
+	})
+}
+
+// ackRecorder 记录是否确认的Consumer
+type ackRecorder struct {
+	acked bool
+}
+
+func (c *ackRecorder) Ack(context.Context) error {
+	c.acked = true
+	return nil
+}
+
+func (c *ackRecorder) ChangeInvisibleDuration(time.Duration) error { return nil }
+
+func (c *ackRecorder) ChangeInvisibleDurationAsync(time.Duration) {}
+
+func TestRouterSubExpressions(t *testing.T) {
+	noop := func(context.Context, *rmq_client.MessageView, Consumer) error { return nil }
+	r := NewRouter()
+	r.Handle("t1", "b || a", noop)
+	r.Handle("t1", "a", noop)
+	r.Handle("t2", "order_*", noop)
+	r.Handle("t2", "x", noop)
+	r.Handle("t3", "*", noop)
+	r.Handle("t4", "", noop).WithProperty("k", "v")
+	if err := r.check(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"t1": "a||b", "t2": "*", "t3": "*", "t4": "*"}
+	got := r.SubExpressions()
+	if len(got) != len(want) {
+		t.Fatalf("SubExpressions = %v", got)
+	}
+	for topic, expression := range want {
+		fe := got[topic]
+		if fe == nil || fe.Expression != expression || fe.ExpressionType != rmq_client.TAG {
+			t.Errorf("topic %s expression = %+v, want %s", topic, fe, expression)
+		}
+	}
+}
+
+func TestRouterCheck(t *testing.T) {
+	noop := func(context.Context, *rmq_client.MessageView, Consumer) error { return nil }
+	cases := map[string]func(r *Router){
+		"no routes":   func(r *Router) {},
+		"no topic":    func(r *Router) { r.Handle(" ", "*", noop) },
+		"no handler":  func(r *Router) { r.Handle("t", "*", nil) },
+		"empty tag":   func(r *Router) { r.Handle("t", "a||", noop) },
+		"bad pattern": func(r *Router) { r.Handle("t", "a[", noop) },
+		"bad policy":  func(r *Router) { r.Handle("t", "*", noop); r.SetFallbackPolicy("x") },
+	}
+	for name, register := range cases {
+		r := NewRouter()
+		register(r)
+		if err := r.check(); ErrorCodeOf(err) != CodeInvalidConfig {
+			t.Errorf("%s: check = %v, want invalid config", name, err)
+		}
+	}
+}
+
+func TestRouterConsume(t *testing.T) {
+	var hit string
+	handler := func(name string) ConsumeFunc {
+		return func(context.Context, *rmq_client.MessageView, Consumer) error {
+			hit = name
+			return nil
+		}
+	}
+	r := NewRouter()
+	r.Handle("t", "vip", handler("vip")).WithProperty("region", "cn")
+	r.Handle("t", "order_*||refund", handler("order"))
+	r.Handle("t", "*", handler("all"))
+	r.Handle("t2", "a", handler("t2"))
+
+	cases := []struct {
+		topic, tag string
+		properties map[string]string
+		want       string
+	}{
+		{"t", "vip", map[string]string{"region": "cn"}, "vip"},
+		{"t", "vip", map[string]string{"region": "us"}, "all"},
+		{"t", "order_created", nil, "order"},
+		{"t", "refund", nil, "order"},
+		{"t", "", nil, "all"},
+		{"t2", "a", nil, "t2"},
+	}
+	for _, c := range cases {
+		hit = ""
+		if err := r.Consume(context.Background(), newTestTaggedMessage(c.topic, c.tag, c.properties), &ackRecorder{}); err != nil {
+			t.Fatal(err)
+		}
+		if hit != c.want {
+			t.Errorf("topic %s tag %s routed to %q, want %q", c.topic, c.tag, hit, c.want)
+		}
+	}
+
+	//没有匹配的路由时按兜底策略处理
+	unmatched := newTestTaggedMessage("t2", "b", nil)
+	err := r.Consume(context.Background(), unmatched, &ackRecorder{})
+	if !IsNonRetryable(err) || ErrorCodeOf(err) != CodeNoRoute {
+		t.Fatalf("default fallback = %v, want non-retryable no route", err)
+	}
+	r.SetFallbackPolicy(FallbackNack)
+	if err = r.Consume(context.Background(), unmatched, &ackRecorder{}); IsNonRetryable(err) || ErrorCodeOf(err) != CodeNoRoute {
+		t.Fatalf("nack fallback = %v, want retryable no route", err)
+	}
+	r.SetFallbackPolicy(FallbackAck)
+	consumer := &ackRecorder{}
+	if err = r.Consume(context.Background(), unmatched, consumer); err != nil || !consumer.acked {
+		t.Fatalf("ack fallback = %v, acked %v", err, consumer.acked)
+	}
+	fallbackErr := errors.New("fallback")
+	r.SetFallback(func(context.Context, *rmq_client.MessageView, Consumer) error { return fallbackErr })
+	if err = r.Consume(context.Background(), unmatched, &ackRecorder{}); err != fallbackErr {
+		t.Fatalf("fallback handler = %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeReceiver 模拟官方客户端的消费者：Receive返回放入的消息，没有消息时最多等待await后返回无新消息，记录确认、调整不可见时间和注销
+type fakeReceiver struct {
+	rmq_client.SimpleConsumer
+	messages chan *rmq_client.MessageView
+	await    time.Duration
+	receives atomic.Int32
+	stopped  atomic.Bool
+
+	mu      sync.Mutex
+	ackErr  error
+	acks    []string
+	changes map[string][]time.Duration
+}
+
+func newFakeReceiver() *fakeReceiver {
+	return &fakeReceiver{
+		messages: make(chan *rmq_client.MessageView, 100),
+		await:    20 * time.Millisecond,
+		changes:  map[string][]time.Duration{},
+	}
+}
+
+// send 放入下次Receive返回的消息
+func (r *fakeReceiver) send(mvs ...*rmq_client.MessageView) {
+	for _, mv := range mvs {
+		r.messages <- mv
+	}
+}
+
+func (r *fakeReceiver) Receive(ctx context.Context, maxMessageNum int32, _ time.Duration) ([]*rmq_client.MessageView, error) {
+	r.receives.Add(1)
+	timer := time.NewTimer(r.await)
+	defer timer.Stop()
+	var mvs []*rmq_client.MessageView
+	select {
+	case mv := <-r.messages:
+		mvs = append(mvs, mv)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, brokerError(v2.Code_MESSAGE_NOT_FOUND)
+	}
+	for int32(len(mvs)) < maxMessageNum {
+		select {
+		case mv := <-r.messages:
+			mvs = append(mvs, mv)
+		default:
+			return mvs, nil
+		}
+	}
+	return mvs, nil
+}
+
+func (r *fakeReceiver) Ack(_ context.Context, mv *rmq_client.MessageView) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ackErr != nil {
+		return r.ackErr
+	}
+	r.acks = append(r.acks, mv.GetMessageId())
+	return nil
+}
+
+func (r *fakeReceiver) ChangeInvisibleDuration(mv *rmq_client.MessageView, d time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes[mv.GetMessageId()] = append(r.changes[mv.GetMessageId()], d)
+	return nil
+}
+
+func (r *fakeReceiver) GracefulStop() error {
+	r.stopped.Store(true)
+	return nil
+}
+
+func (r *fakeReceiver) ackedIds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.acks...)
+}
+
+func (r *fakeReceiver) changed(id string) []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration(nil), r.changes[id]...)
+}
+
+// startTestConsumer 用fakeReceiver启动接收循环，选项的默认值和startConsumer相同，测试结束时停止
+func startTestConsumer(t *testing.T, ctx context.Context, receiver *fakeReceiver, consumeFunc ConsumeFunc, batchFunc BatchConsumeFunc, oFunc ...ConsumerOptionFunc) *simpleConsumer {
+	t.Helper()
+	oFunc = append([]ConsumerOptionFunc{WithConsumerOptionPollPolicy(PollPolicy{IdleDelay: time.Millisecond, ErrorInitial: time.Millisecond})}, oFunc...)
+	options := newConsumerOptions(oFunc...)
+	if options.PrefetchSize == 0 {
+		options.PrefetchSize = int(options.MaxMessageNum)
+	}
+	if batchFunc != nil {
+		options.AckMode = AckAuto
+		if options.BatchSize == 0 {
+			options.BatchSize = int(options.MaxMessageNum)
+		}
+	}
+	c := runSimpleConsumer(ctx, &Config{ConsumerGroup: "cg_test"}, options, receiver, consumeFunc, batchFunc, func() {})
+	t.Cleanup(func() {
+		c.stopReceiving()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = c.wait(ctx)
+		_ = c.shutdown()
+	})
+	return c
+}
+
+// waitFor 等待cond成立，超时时测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSimpleConsumerParentCancel(t *testing.T) {
+	receiver := newFakeReceiver()
+	//Receive一直等待，只能被ctx中断
+	receiver.await = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := startTestConsumer(t, ctx, receiver, func(context.Context, *rmq_client.MessageView, Consumer) error {
+		return nil
+	}, nil)
+	waitFor(t, "the first receive", func() bool {
+		return receiver.receives.Load() > 0
+	})
+
+	cancel()
+	select {
+	case <-c.loopDone:
+	case <-time.After(time.Second):
+		t.Fatal("receive loop did not exit after the parent ctx was canceled")
+	}
+	waitFor(t, "the consumer to shut down", receiver.stopped.Load)
+	if c.Health().Started {
+		t.Fatal("consumer still started after the parent ctx was canceled")
+	}
+}
+
+func TestSimpleConsumerStopTimeout(t *testing.T) {
+	cases := map[string]struct {
+		handlerTime time.Duration
+		wantTimeout bool
+	}{
+		"drained":  {20 * time.Millisecond, false},
+		"deadline": {time.Hour, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			receiver := newFakeReceiver()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+			var finished atomic.Bool
+			c := startTestConsumer(t, context.Background(), receiver, func(context.Context, *rmq_client.MessageView, Consumer) error {
+				close(started)
+				select {
+				case <-time.After(tc.handlerTime):
+					finished.Store(true)
+				case <-release:
+				}
+				return nil
+			}, nil, WithConsumerOptionStopTimeout(100*time.Millisecond))
+			receiver.send(newTestMessageView("t", "m1", "", ""))
+			<-started
+
+			//SimpleConsume返回的stopFunc
+			stopFunc := c.Stop
+			begin := time.Now()
+			err := stopFunc()
+			elapsed := time.Since(begin)
+			if elapsed > time.Second {
+				t.Fatalf("stop took %v, want at most about StopTimeout", elapsed)
+			}
+			if !receiver.stopped.Load() {
+				t.Fatal("consumer was not shut down")
+			}
+			if !tc.wantTimeout {
+				if err != nil || !finished.Load() {
+					t.Fatalf("err = %v, finished = %v, want the handler to finish before stop returns", err, finished.Load())
+				}
+				return
+			}
+			if ErrorCodeOf(err) != CodeShutdownFailed || !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("err = %v, want a shutdown timeout error", err)
+			}
+			if elapsed < 100*time.Millisecond {
+				t.Fatalf("stop returned after %v, before StopTimeout", elapsed)
+			}
+		})
+	}
+}
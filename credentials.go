@@ -0,0 +1,280 @@
+package rocketmq_client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/apache/rocketmq-clients/golang/v5/credentials"
+	innerMD "github.com/apache/rocketmq-clients/golang/v5/metadata"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/google/uuid"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// credentialsPollInterval 运行中的客户端向凭证提供者获取最新凭证的间隔
+var credentialsPollInterval = 10 * time.Second
+
+// Credentials 访问凭证
+type Credentials struct {
+	AccessKey     string    `json:"accessKey"`
+	AccessSecret  string    `json:"accessSecret"`
+	SecurityToken string    `json:"securityToken"` //临时凭证的token，可选
+	Expiration    time.Time `json:"expiration"`    //过期时间，为零表示不过期
+}
+
+// expired 凭证在after之后是否已过期
+func (c *Credentials) expiredAfter(after time.Duration) bool {
+	return !c.Expiration.IsZero() && time.Until(c.Expiration) <= after
+}
+
+// CredentialsProvider 凭证提供者
+// 运行中的生产者和消费者会定期调用Retrieve，凭证变化时无需重启即可生效，实现需要自行做好缓存
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (*Credentials, error)
+}
+
+// NewStaticCredentialsProvider 固定凭证
+func NewStaticCredentialsProvider(accessKey, accessSecret, securityToken string) CredentialsProvider {
+	return &staticCredentialsProvider{
+		creds: &Credentials{
+			AccessKey:     accessKey,
+			AccessSecret:  accessSecret,
+			SecurityToken: securityToken,
+		},
+	}
+}
+
+type staticCredentialsProvider struct {
+	creds *Credentials
+}
+
+func (p *staticCredentialsProvider) Retrieve(_ context.Context) (*Credentials, error) {
+	return p.creds, nil
+}
+
+// NewEnvCredentialsProvider 从环境变量读取凭证，每次获取时都会重新读取
+// 变量名为<PREFIX>_ACCESS_KEY、<PREFIX>_ACCESS_SECRET、<PREFIX>_SECURITY_TOKEN，prefix为空时为ROCKETMQ
+func NewEnvCredentialsProvider(prefix string) CredentialsProvider {
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	if prefix == "" {
+		prefix = "ROCKETMQ"
+	}
+	return &envCredentialsProvider{prefix: prefix + "_"}
+}
+
+type envCredentialsProvider struct {
+	prefix string
+}
+
+func (p *envCredentialsProvider) Retrieve(_ context.Context) (*Credentials, error) {
+	c := &Credentials{
+		AccessKey:     os.Getenv(p.prefix + "ACCESS_KEY"),
+		AccessSecret:  os.Getenv(p.prefix + "ACCESS_SECRET"),
+		SecurityToken: os.Getenv(p.prefix + "SECURITY_TOKEN"),
+	}
+	if c.AccessKey == "" || c.AccessSecret == "" {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgRequired, p.prefix+"ACCESS_KEY/"+p.prefix+"ACCESS_SECRET")
+	}
+	return c, nil
+}
+
+// NewFileCredentialsProvider 从文件读取凭证，文件修改后会重新读取
+// 文件格式支持json、yaml、toml等，字段为accessKey、accessSecret、securityToken、expiration（RFC3339格式，可选）
+func NewFileCredentialsProvider(path string) CredentialsProvider {
+	return &fileCredentialsProvider{path: path}
+}
+
+type fileCredentialsProvider struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   *Credentials
+}
+
+func (p *fileCredentialsProvider) Retrieve(_ context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgCredentialsRetrieveFailed, p.path).wrap(err)
+	}
+	if p.creds != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.creds, nil
+	}
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgCredentialsRetrieveFailed, p.path).wrap(err)
+	}
+	j, err := gjson.LoadContent(content, true)
+	if err != nil {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgCredentialsRetrieveFailed, p.path).wrap(err)
+	}
+	c := &Credentials{
+		AccessKey:     j.Get("accessKey").String(),
+		AccessSecret:  j.Get("accessSecret").String(),
+		SecurityToken: j.Get("securityToken").String(),
+	}
+	if v := j.Get("expiration").String(); v != "" {
+		if c.Expiration, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, newError(CodeCredentialsUnavailable, ErrMsgCredentialsRetrieveFailed, p.path).wrap(err)
+		}
+	}
+	if c.AccessKey == "" || c.AccessSecret == "" {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgRequired, p.path+":accessKey/accessSecret")
+	}
+	p.creds, p.modTime, p.size = c, info.ModTime(), info.Size()
+	return c, nil
+}
+
+// RefreshingCredentialsProvider 缓存凭证，并在凭证过期前refreshBefore时间内重新获取
+// 重新获取失败时，只要旧凭证还没过期就继续使用旧凭证
+type RefreshingCredentialsProvider struct {
+	provider      CredentialsProvider
+	refreshBefore time.Duration
+	mu            sync.Mutex
+	current       *Credentials
+}
+
+// NewRefreshingCredentialsProvider 包装一个会签发临时凭证的提供者，refreshBefore<=0时为5分钟
+func NewRefreshingCredentialsProvider(provider CredentialsProvider, refreshBefore time.Duration) *RefreshingCredentialsProvider {
+	if refreshBefore <= 0 {
+		refreshBefore = 5 * time.Minute
+	}
+	return &RefreshingCredentialsProvider{
+		provider:      provider,
+		refreshBefore: refreshBefore,
+	}
+}
+
+func (p *RefreshingCredentialsProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != nil && !p.current.expiredAfter(p.refreshBefore) {
+		return p.current, nil
+	}
+	c, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		if p.current != nil && !p.current.expiredAfter(0) {
+			return p.current, nil
+		}
+		return nil, err
+	}
+	if c.expiredAfter(0) {
+		return nil, newError(CodeCredentialsUnavailable, ErrMsgCredentialsExpired, c.Expiration.Format(time.RFC3339))
+	}
+	p.current = c
+	return c, nil
+}
+
+// Expire 让缓存的凭证失效，下次获取时重新向提供者获取
+func (p *RefreshingCredentialsProvider) Expire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = nil
+}
+
+// credentialPlaceholderPrefix 使用凭证提供者时传给官方客户端的占位accessKey前缀
+const credentialPlaceholderPrefix = "rmqc-session-"
+
+// credentialsSessions 按占位accessKey记录的凭证会话
+var credentialsSessions sync.Map
+
+// credentialsSession 使用凭证提供者的单个客户端实例的当前凭证
+// 官方客户端只拿到唯一的占位accessKey，不会修改它的配置；请求发出前由连接的拦截器按占位accessKey
+// 找到会话，用当前凭证重新签名并附加security token。官方客户端上报指标的连接不经过拦截器，不会重新签名
+type credentialsSession struct {
+	id      string
+	current atomic.Pointer[Credentials]
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// newCredentialsSession 获取初始凭证并注册会话，后台定期刷新凭证，需调用close释放
+func newCredentialsSession(ctx context.Context, cfg *Config) (*credentialsSession, error) {
+	c, err := cfg.CredentialsProvider.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &credentialsSession{
+		id:   credentialPlaceholderPrefix + uuid.NewString(),
+		done: make(chan struct{}),
+	}
+	s.current.Store(c)
+	credentialsSessions.Store(s.id, s)
+	var watchCtx context.Context
+	watchCtx, s.cancel = context.WithCancel(context.Background())
+	go s.watch(watchCtx, cfg)
+	return s, nil
+}
+
+// placeholder 传给官方客户端的凭证
+func (s *credentialsSession) placeholder() *credentials.SessionCredentials {
+	return &credentials.SessionCredentials{
+		AccessKey:    s.id,
+		AccessSecret: s.id,
+	}
+}
+
+// watch 定期从凭证提供者获取凭证，变化时替换当前凭证
+func (s *credentialsSession) watch(ctx context.Context, cfg *Config) {
+	defer close(s.done)
+	ticker := time.NewTicker(credentialsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c, err := cfg.CredentialsProvider.Retrieve(ctx)
+		if err != nil {
+			logEvent(ctx, cfg, slog.LevelError, opCredentials, "刷新凭证失败", attrError(err))
+			continue
+		}
+		old := s.current.Load()
+		if old.AccessKey == c.AccessKey && old.AccessSecret == c.AccessSecret && old.SecurityToken == c.SecurityToken {
+			continue
+		}
+		s.current.Store(c)
+		logEvent(ctx, cfg, slog.LevelInfo, opCredentials, "凭证已刷新", slog.Time("expiration", c.Expiration))
+	}
+}
+
+// close 停止刷新并注销会话
+func (s *credentialsSession) close() {
+	s.cancel()
+	<-s.done
+	credentialsSessions.Delete(s.id)
+}
+
+// lookupCredentialsSession 按请求签名中的accessKey查找凭证会话
+func lookupCredentialsSession(accessKey string) (*credentialsSession, bool) {
+	if !strings.HasPrefix(accessKey, credentialPlaceholderPrefix) {
+		return nil, false
+	}
+	v, ok := credentialsSessions.Load(accessKey)
+	if !ok {
+		return nil, false
+	}
+	return v.(*credentialsSession), true
+}
+
+// signAuthorization 按官方客户端的方式生成请求签名，dateTime为x-mq-date-time请求头的值
+func signAuthorization(accessKey, accessSecret, dateTime string) string {
+	h := hmac.New(sha1.New, []byte(accessSecret))
+	h.Write([]byte(dateTime))
+	return fmt.Sprintf("%s %s=%s/%s/%s, %s=%s, %s=%s",
+		innerMD.EncryptHeader,
+		innerMD.Credential, accessKey, "", innerMD.Rocketmq,
+		innerMD.SignedHeaders, innerMD.DateTime,
+		innerMD.Signature, hex.EncodeToString(h.Sum(nil)),
+	)
+}
@@ -0,0 +1,170 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	innerMD "github.com/apache/rocketmq-clients/golang/v5/metadata"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mutableCredentialsProvider struct {
+	mu    sync.Mutex
+	creds *Credentials
+	err   error
+	calls int
+}
+
+func (p *mutableCredentialsProvider) set(c *Credentials, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.creds, p.err = c, err
+}
+
+func (p *mutableCredentialsProvider) Retrieve(context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return p.creds, p.err
+}
+
+// signedContext 模拟官方客户端签名后的请求
+func signedContext(accessKey, accessSecret string) context.Context {
+	now := time.Now().Format("20060102T150405Z")
+	return metadata.AppendToOutgoingContext(context.Background(),
+		innerMD.DateTime, now,
+		innerMD.Authorization, signAuthorization(accessKey, accessSecret, now),
+	)
+}
+
+func outgoingHeader(ctx context.Context, key string) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func TestCredentialsSessionResign(t *testing.T) {
+	old := credentialsPollInterval
+	credentialsPollInterval = 10 * time.Millisecond
+	defer func() {
+		credentialsPollInterval = old
+	}()
+
+	p1 := &mutableCredentialsProvider{creds: &Credentials{AccessKey: "ak", AccessSecret: "sk1", SecurityToken: "token1"}}
+	p2 := &mutableCredentialsProvider{creds: &Credentials{AccessKey: "ak", AccessSecret: "sk2", SecurityToken: "token2"}}
+	rc1, release1, err := getRmqCfg(context.Background(), &Config{CredentialsProvider: p1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc2, release2, err := getRmqCfg(context.Background(), &Config{CredentialsProvider: p2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release2()
+	if rc1.Credentials.AccessKey == "ak" || rc1.Credentials.AccessKey == rc2.Credentials.AccessKey {
+		t.Fatalf("placeholder access key not unique: %q %q", rc1.Credentials.AccessKey, rc2.Credentials.AccessKey)
+	}
+
+	check := func(rcKey, rcSecret, wantSecret, wantToken string) {
+		t.Helper()
+		ctx := withSessionCredentials(signedContext(rcKey, rcSecret))
+		want := signAuthorization("ak", wantSecret, outgoingHeader(ctx, innerMD.DateTime))
+		if got := outgoingHeader(ctx, authorizationHeader); got != want {
+			t.Fatalf("authorization = %q, want %q", got, want)
+		}
+		if got := outgoingHeader(ctx, sessionTokenHeader); got != wantToken {
+			t.Fatalf("token = %q, want %q", got, wantToken)
+		}
+	}
+	//同一accessKey的两个实例各用各的凭证
+	check(rc1.Credentials.AccessKey, rc1.Credentials.AccessSecret, "sk1", "token1")
+	check(rc2.Credentials.AccessKey, rc2.Credentials.AccessSecret, "sk2", "token2")
+
+	//凭证轮换后不修改官方客户端的配置，按新凭证签名
+	placeholder := *rc1.Credentials
+	p1.set(&Credentials{AccessKey: "ak", AccessSecret: "sk3", SecurityToken: "token3"}, nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ctx := withSessionCredentials(signedContext(rc1.Credentials.AccessKey, rc1.Credentials.AccessSecret))
+		if outgoingHeader(ctx, sessionTokenHeader) == "token3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("credentials not rotated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if *rc1.Credentials != placeholder {
+		t.Fatal("sdk config mutated")
+	}
+	check(rc2.Credentials.AccessKey, rc2.Credentials.AccessSecret, "sk2", "token2")
+
+	//刷新失败时继续使用当前凭证
+	p1.set(nil, errors.New("unavailable"))
+	time.Sleep(30 * time.Millisecond)
+	check(rc1.Credentials.AccessKey, rc1.Credentials.AccessSecret, "sk3", "token3")
+
+	release1()
+	if _, ok := lookupCredentialsSession(rc1.Credentials.AccessKey); ok {
+		t.Fatal("session not released")
+	}
+	//非占位的accessKey原样发送
+	ctx := signedContext("static", "secret")
+	if got := withSessionCredentials(ctx); got != ctx {
+		t.Fatal("static credentials changed")
+	}
+}
+
+func TestRefreshingCredentialsProvider(t *testing.T) {
+	inner := &mutableCredentialsProvider{creds: &Credentials{AccessKey: "a", AccessSecret: "s", Expiration: time.Now().Add(time.Hour)}}
+	p := NewRefreshingCredentialsProvider(inner, time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, err := p.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("calls = %d, want 1", inner.calls)
+	}
+	//快过期时重新获取，失败时继续使用未过期的旧凭证
+	p.current.Expiration = time.Now().Add(30 * time.Second)
+	inner.set(nil, errors.New("unavailable"))
+	if c, err := p.Retrieve(context.Background()); err != nil || c.AccessKey != "a" {
+		t.Fatalf("got %v %v, want old credentials", c, err)
+	}
+	p.Expire()
+	if _, err := p.Retrieve(context.Background()); err == nil {
+		t.Fatal("expected error after Expire")
+	}
+	inner.set(&Credentials{AccessKey: "a", AccessSecret: "s", Expiration: time.Now().Add(-time.Second)}, nil)
+	if _, err := p.Retrieve(context.Background()); ErrorCodeOf(err) != CodeCredentialsUnavailable {
+		t.Fatalf("expired credentials err = %v", err)
+	}
+}
+
+func TestFileCredentialsProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.json")
+	p := NewFileCredentialsProvider(path)
+	if _, err := p.Retrieve(context.Background()); ErrorCodeOf(err) != CodeCredentialsUnavailable {
+		t.Fatalf("missing file err = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"accessKey":"a","accessSecret":"s","securityToken":"t"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Retrieve(context.Background())
+	if err != nil || c.AccessKey != "a" || c.SecurityToken != "t" {
+		t.Fatalf("got %+v %v", c, err)
+	}
+	if err = os.WriteFile(path, []byte(`{"accessKey":"b","accessSecret":"s2","expiration":"2100-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = p.Retrieve(context.Background()); err != nil || c.AccessKey != "b" || c.Expiration.Year() != 2100 {
+		t.Fatalf("got %+v %v", c, err)
+	}
+}
@@ -0,0 +1,196 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	grpc_codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorClass 错误分类，服务端错误按返回码分类，本客户端的错误按错误码分类
+type ErrorClass string
+
+const (
+	ErrorClassNone                 ErrorClass = ""                       //没有错误
+	ErrorClassUnknown              ErrorClass = "UNKNOWN"                //无法识别的错误
+	ErrorClassNoNewMessage         ErrorClass = "NO_NEW_MESSAGE"         //没有新消息
+	ErrorClassThrottled            ErrorClass = "THROTTLED"              //触发了流控
+	ErrorClassTimeout              ErrorClass = "TIMEOUT"                //请求超时
+	ErrorClassUnavailable          ErrorClass = "UNAVAILABLE"            //服务端暂时不可用
+	ErrorClassServerError          ErrorClass = "SERVER_ERROR"           //服务端内部错误
+	ErrorClassUnauthorized         ErrorClass = "UNAUTHORIZED"           //鉴权失败
+	ErrorClassForbidden            ErrorClass = "FORBIDDEN"              //没有权限
+	ErrorClassTopicNotFound        ErrorClass = "TOPIC_NOT_FOUND"        //主题不存在
+	ErrorClassGroupNotFound        ErrorClass = "GROUP_NOT_FOUND"        //消费者分组不存在
+	ErrorClassMessageTooLarge      ErrorClass = "MESSAGE_TOO_LARGE"      //消息体或属性过大
+	ErrorClassBadFilterExpression  ErrorClass = "BAD_FILTER_EXPRESSION"  //过滤表达式不合法
+	ErrorClassIllegalMessageGroup  ErrorClass = "ILLEGAL_MESSAGE_GROUP"  //消息组不合法
+	ErrorClassInvalidReceiptHandle ErrorClass = "INVALID_RECEIPT_HANDLE" //消息句柄失效，一般是不可见时间已过
+	ErrorClassBadRequest           ErrorClass = "BAD_REQUEST"            //其他请求参数错误
+	ErrorClassUnsupported          ErrorClass = "UNSUPPORTED"            //服务端不支持
+	ErrorClassInvalidClientRequest ErrorClass = "INVALID_CLIENT_REQUEST" //本客户端校验不通过的请求
+	ErrorClassCanceled             ErrorClass = "CANCELED"               //请求被取消
+)
+
+// ClassifyError 对错误进行分类，支持被包装过的错误
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.Code {
+		case CodeThrottled:
+			return ErrorClassThrottled
+		case CodeInvalidConfig, CodeInvalidMessage, CodeInvalidArgument, CodeProducerNotStarted,
+			CodeUnsupportedTopicType, CodeTopicTypeMismatch, CodeTopicNotRegistered:
+			return ErrorClassInvalidClientRequest
+		case CodeCredentialsUnavailable:
+			return ErrorClassUnauthorized
+		}
+	}
+	if code, ok := BrokerCode(err); ok {
+		return classifyBrokerCode(code)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case grpc_codes.DeadlineExceeded:
+			return ErrorClassTimeout
+		case grpc_codes.Unavailable, grpc_codes.Aborted:
+			return ErrorClassUnavailable
+		case grpc_codes.ResourceExhausted:
+			return ErrorClassThrottled
+		case grpc_codes.Unauthenticated:
+			return ErrorClassUnauthorized
+		case grpc_codes.PermissionDenied:
+			return ErrorClassForbidden
+		case grpc_codes.Canceled:
+			return ErrorClassCanceled
+		case grpc_codes.Internal:
+			return ErrorClassServerError
+		}
+	}
+	return ErrorClassUnknown
+}
+
+func classifyBrokerCode(code v2.Code) ErrorClass {
+	switch code {
+	case v2.Code_MESSAGE_NOT_FOUND:
+		return ErrorClassNoNewMessage
+	case v2.Code_TOO_MANY_REQUESTS:
+		return ErrorClassThrottled
+	case v2.Code_REQUEST_TIMEOUT, v2.Code_PROXY_TIMEOUT, v2.Code_MASTER_PERSISTENCE_TIMEOUT, v2.Code_SLAVE_PERSISTENCE_TIMEOUT:
+		return ErrorClassTimeout
+	case v2.Code_HA_NOT_AVAILABLE:
+		return ErrorClassUnavailable
+	case v2.Code_UNAUTHORIZED:
+		return ErrorClassUnauthorized
+	case v2.Code_FORBIDDEN:
+		return ErrorClassForbidden
+	case v2.Code_TOPIC_NOT_FOUND, v2.Code_ILLEGAL_TOPIC:
+		return ErrorClassTopicNotFound
+	case v2.Code_CONSUMER_GROUP_NOT_FOUND, v2.Code_ILLEGAL_CONSUMER_GROUP:
+		return ErrorClassGroupNotFound
+	case v2.Code_PAYLOAD_TOO_LARGE, v2.Code_MESSAGE_BODY_TOO_LARGE,
+		v2.Code_REQUEST_HEADER_FIELDS_TOO_LARGE, v2.Code_MESSAGE_PROPERTIES_TOO_LARGE:
+		return ErrorClassMessageTooLarge
+	case v2.Code_ILLEGAL_FILTER_EXPRESSION:
+		return ErrorClassBadFilterExpression
+	case v2.Code_ILLEGAL_MESSAGE_GROUP:
+		return ErrorClassIllegalMessageGroup
+	case v2.Code_INVALID_RECEIPT_HANDLE:
+		return ErrorClassInvalidReceiptHandle
+	case v2.Code_NOT_IMPLEMENTED, v2.Code_UNSUPPORTED, v2.Code_VERSION_UNSUPPORTED, v2.Code_VERIFY_FIFO_MESSAGE_UNSUPPORTED:
+		return ErrorClassUnsupported
+	}
+	switch {
+	case code >= 40000 && code < 50000:
+		return ErrorClassBadRequest
+	case code >= 50000 && code < 60000:
+		return ErrorClassServerError
+	}
+	return ErrorClassUnknown
+}
+
+// Retryable 该分类的错误重试是否可能成功，无法识别的错误不重试
+func (c ErrorClass) Retryable() bool {
+	switch c {
+	case ErrorClassThrottled, ErrorClassTimeout, ErrorClassUnavailable, ErrorClassServerError:
+		return true
+	}
+	return false
+}
+
+// BrokerCode 获取错误链中服务端返回的错误码
+func BrokerCode(err error) (v2.Code, bool) {
+	var e *rmq_client.ErrRpcStatus
+	if errors.As(err, &e) {
+		return v2.Code(e.GetCode()), true
+	}
+	return 0, false
+}
+
+// IsRetryableError 错误是否可重试，如流控、超时、服务端暂不可用等
+// 消费者接收失败时的日志级别和生产者是否切换endpoint都按这里判断
+func IsRetryableError(err error) bool {
+	return err != nil && ClassifyError(err).Retryable()
+}
+
+// IsTimeout 是否请求超时
+func IsTimeout(err error) bool {
+	return ClassifyError(err) == ErrorClassTimeout
+}
+
+// IsAuthError 是否鉴权失败或没有权限
+func IsAuthError(err error) bool {
+	c := ClassifyError(err)
+	return c == ErrorClassUnauthorized || c == ErrorClassForbidden
+}
+
+// IsTopicNotFound 是否主题不存在
+func IsTopicNotFound(err error) bool {
+	return ClassifyError(err) == ErrorClassTopicNotFound
+}
+
+// IsConsumerGroupNotFound 是否消费者分组不存在
+func IsConsumerGroupNotFound(err error) bool {
+	return ClassifyError(err) == ErrorClassGroupNotFound
+}
+
+// IsMessageTooLarge 是否消息体或属性过大
+func IsMessageTooLarge(err error) bool {
+	return ClassifyError(err) == ErrorClassMessageTooLarge
+}
+
+// IsBadFilterExpression 是否过滤表达式不合法
+func IsBadFilterExpression(err error) bool {
+	return ClassifyError(err) == ErrorClassBadFilterExpression
+}
+
+// IsIllegalMessageGroup 是否消息组不合法
+func IsIllegalMessageGroup(err error) bool {
+	return ClassifyError(err) == ErrorClassIllegalMessageGroup
+}
+
+// IsInvalidReceiptHandle 是否消息句柄失效，一般是消费时间超过了不可见时间
+func IsInvalidReceiptHandle(err error) bool {
+	return ClassifyError(err) == ErrorClassInvalidReceiptHandle
+}
+
+// IsTooManyRequest 是否触发了流控
+func IsTooManyRequest(err error) bool {
+	return ClassifyError(err) == ErrorClassThrottled
+}
+
+// IsNoNewMessage 是否没有新消息
+func IsNoNewMessage(err error) bool {
+	return ClassifyError(err) == ErrorClassNoNewMessage
+}
@@ -0,0 +1,70 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	grpc_codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func brokerError(code v2.Code) error {
+	return &rmq_client.ErrRpcStatus{Code: int32(code), Message: code.String()}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		class     ErrorClass
+		retryable bool
+	}{
+		{"nil", nil, ErrorClassNone, false},
+		{"plain", errors.New("boom"), ErrorClassUnknown, false},
+		{"no new message", brokerError(v2.Code_MESSAGE_NOT_FOUND), ErrorClassNoNewMessage, false},
+		{"wrapped throttled", fmt.Errorf("send: %w", brokerError(v2.Code_TOO_MANY_REQUESTS)), ErrorClassThrottled, true},
+		{"proxy timeout", brokerError(v2.Code_PROXY_TIMEOUT), ErrorClassTimeout, true},
+		{"unauthorized", brokerError(v2.Code_UNAUTHORIZED), ErrorClassUnauthorized, false},
+		{"forbidden", brokerError(v2.Code_FORBIDDEN), ErrorClassForbidden, false},
+		{"topic not found", brokerError(v2.Code_TOPIC_NOT_FOUND), ErrorClassTopicNotFound, false},
+		{"group not found", brokerError(v2.Code_CONSUMER_GROUP_NOT_FOUND), ErrorClassGroupNotFound, false},
+		{"body too large", brokerError(v2.Code_MESSAGE_BODY_TOO_LARGE), ErrorClassMessageTooLarge, false},
+		{"bad filter", brokerError(v2.Code_ILLEGAL_FILTER_EXPRESSION), ErrorClassBadFilterExpression, false},
+		{"bad group", brokerError(v2.Code_ILLEGAL_MESSAGE_GROUP), ErrorClassIllegalMessageGroup, false},
+		{"receipt handle", brokerError(v2.Code_INVALID_RECEIPT_HANDLE), ErrorClassInvalidReceiptHandle, false},
+		{"other 4xx", brokerError(v2.Code(40099)), ErrorClassBadRequest, false},
+		{"other 5xx", brokerError(v2.Code(50099)), ErrorClassServerError, true},
+		{"deadline", context.DeadlineExceeded, ErrorClassTimeout, true},
+		{"canceled", fmt.Errorf("x: %w", context.Canceled), ErrorClassCanceled, false},
+		{"grpc unavailable", status.Error(grpc_codes.Unavailable, "down"), ErrorClassUnavailable, true},
+		{"grpc exhausted", status.Error(grpc_codes.ResourceExhausted, "slow"), ErrorClassThrottled, true},
+		{"grpc unknown", status.Error(grpc_codes.Unknown, "?"), ErrorClassUnknown, false},
+		{"client throttled", newError(CodeThrottled, ErrMsgThrottled), ErrorClassThrottled, true},
+		{"client invalid", newError(CodeInvalidMessage, ErrMsgRequired, "Topic"), ErrorClassInvalidClientRequest, false},
+	}
+	for _, c := range cases {
+		if got := ClassifyError(c.err); got != c.class {
+			t.Errorf("%s: class = %q, want %q", c.name, got, c.class)
+		}
+		if got := IsRetryableError(c.err); got != c.retryable {
+			t.Errorf("%s: retryable = %v, want %v", c.name, got, c.retryable)
+		}
+	}
+}
+
+func TestWrapSendError(t *testing.T) {
+	err := wrapSendError(brokerError(v2.Code_TOO_MANY_REQUESTS))
+	if !errors.Is(err, ErrThrottled) {
+		t.Fatalf("throttled error not wrapped: %v", err)
+	}
+	if wrapSendError(err) != err {
+		t.Fatal("ErrThrottled wrapped twice")
+	}
+	plain := brokerError(v2.Code_TOPIC_NOT_FOUND)
+	if wrapSendError(plain) != plain {
+		t.Fatal("non throttled error changed")
+	}
+}
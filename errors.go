@@ -0,0 +1,320 @@
+package rocketmq_client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrorCode 错误码，值稳定，可用于程序判断和日志检索
+type ErrorCode string
+
+const (
+	CodeInvalidConfig          ErrorCode = "INVALID_CONFIG"          //配置不合法
+	CodeInvalidMessage         ErrorCode = "INVALID_MESSAGE"         //消息不合法
+	CodeInvalidArgument        ErrorCode = "INVALID_ARGUMENT"        //参数不合法
+	CodeProducerNotStarted     ErrorCode = "PRODUCER_NOT_STARTED"    //生产者未初始化或已注销
+	CodeUnsupportedTopicType   ErrorCode = "UNSUPPORTED_TOPIC_TYPE"  //不支持的主题类型
+	CodeTopicTypeMismatch      ErrorCode = "TOPIC_TYPE_MISMATCH"     //发送类型和主题类型不一致
+	CodeThrottled              ErrorCode = "THROTTLED"               //触发了服务端流控
+	CodeCredentialsUnavailable ErrorCode = "CREDENTIALS_UNAVAILABLE" //获取凭证失败
+	CodeTopicNotRegistered     ErrorCode = "TOPIC_NOT_REGISTERED"    //主题未在注册表中注册
+	CodePreflightFailed        ErrorCode = "PREFLIGHT_FAILED"        //启动前预检不通过
+	CodeShutdownFailed         ErrorCode = "SHUTDOWN_FAILED"         //优雅退出未能完成
+	CodeLeaseLost              ErrorCode = "LEASE_LOST"              //消息不可见时间续期失败或消费时间超过MaxProcessingTime
+	CodeConsumeFailed          ErrorCode = "CONSUME_FAILED"          //推送消费的监听方法返回失败或panic
+	CodeNoRoute                ErrorCode = "NO_ROUTE"                //消息没有匹配的路由
+)
+
+// 可用errors.Is判断的哨兵错误，只比较错误码
+var (
+	ErrInvalidConfig          = &Error{Code: CodeInvalidConfig}
+	ErrInvalidMessage         = &Error{Code: CodeInvalidMessage}
+	ErrInvalidArgument        = &Error{Code: CodeInvalidArgument}
+	ErrProducerNotStarted     = &Error{Code: CodeProducerNotStarted}
+	ErrUnsupportedTopicType   = &Error{Code: CodeUnsupportedTopicType}
+	ErrTopicTypeMismatch      = &Error{Code: CodeTopicTypeMismatch}
+	ErrThrottled              = &Error{Code: CodeThrottled}
+	ErrCredentialsUnavailable = &Error{Code: CodeCredentialsUnavailable}
+	ErrTopicNotRegistered     = &Error{Code: CodeTopicNotRegistered}
+	ErrPreflightFailed        = &Error{Code: CodePreflightFailed}
+	ErrShutdownFailed         = &Error{Code: CodeShutdownFailed}
+	ErrLeaseLost              = &Error{Code: CodeLeaseLost}
+	ErrConsumeFailed          = &Error{Code: CodeConsumeFailed}
+	ErrNoRoute                = &Error{Code: CodeNoRoute}
+)
+
+// ErrorMessageKey 错误信息的模板key，可通过RegisterErrorMessages注册其他语言的模板
+type ErrorMessageKey string
+
+const (
+	ErrMsgRequired                  ErrorMessageKey = "required"
+	ErrMsgNegative                  ErrorMessageKey = "negative"
+	ErrMsgNonPositive               ErrorMessageKey = "non_positive"
+	ErrMsgInvalidDuration           ErrorMessageKey = "invalid_duration"
+	ErrMsgNonPositiveDuration       ErrorMessageKey = "non_positive_duration"
+	ErrMsgInvalidInt                ErrorMessageKey = "invalid_int"
+	ErrMsgInvalidBool               ErrorMessageKey = "invalid_bool"
+	ErrMsgInvalidTopicType          ErrorMessageKey = "invalid_topic_type"
+	ErrMsgInvalidFilterType         ErrorMessageKey = "invalid_filter_type"
+	ErrMsgConfigNodeMissing         ErrorMessageKey = "config_node_missing"
+	ErrMsgConfigReadFailed          ErrorMessageKey = "config_read_failed"
+	ErrMsgConfigDecodeFailed        ErrorMessageKey = "config_decode_failed"
+	ErrMsgInstanceInvalid           ErrorMessageKey = "instance_invalid"
+	ErrMsgConsumerGroupRequired     ErrorMessageKey = "consumer_group_required"
+	ErrMsgFifoGroupRequired         ErrorMessageKey = "fifo_group_required"
+	ErrMsgDelayTimeRequired         ErrorMessageKey = "delay_time_required"
+	ErrMsgProducerNotStarted        ErrorMessageKey = "producer_not_started"
+	ErrMsgMethodNotSupported        ErrorMessageKey = "method_not_supported"
+	ErrMsgTopicTypeMismatch         ErrorMessageKey = "topic_type_mismatch"
+	ErrMsgThrottled                 ErrorMessageKey = "throttled"
+	ErrMsgCredentialsRetrieveFailed ErrorMessageKey = "credentials_retrieve_failed"
+	ErrMsgCredentialsExpired        ErrorMessageKey = "credentials_expired"
+	ErrMsgTLSPlaintextConflict      ErrorMessageKey = "tls_plaintext_conflict"
+	ErrMsgTLSFileInvalid            ErrorMessageKey = "tls_file_invalid"
+	ErrMsgTLSConflict               ErrorMessageKey = "tls_conflict"
+	ErrMsgInvalidEndpoint           ErrorMessageKey = "invalid_endpoint"
+	ErrMsgNoAvailableEndpoint       ErrorMessageKey = "no_available_endpoint"
+	ErrMsgTopicNotRegistered        ErrorMessageKey = "topic_not_registered"
+	ErrMsgTopicsNotCovered          ErrorMessageKey = "topics_not_covered"
+	ErrMsgTopicTypeConflict         ErrorMessageKey = "topic_type_conflict"
+	ErrMsgInvalidFilterExpression   ErrorMessageKey = "invalid_filter_expression"
+	ErrMsgInvalidPreflightMode      ErrorMessageKey = "invalid_preflight_mode"
+	ErrMsgPreflightFailed           ErrorMessageKey = "preflight_failed"
+	ErrMsgInvalidAckMode            ErrorMessageKey = "invalid_ack_mode"
+	ErrMsgInvalidNackStrategy       ErrorMessageKey = "invalid_nack_strategy"
+	ErrMsgInstanceNotConfigured     ErrorMessageKey = "instance_not_configured"
+	ErrMsgManagerRunning            ErrorMessageKey = "manager_running"
+	ErrMsgShutdownStepFailed        ErrorMessageKey = "shutdown_step_failed"
+	ErrMsgShutdownInFlight          ErrorMessageKey = "shutdown_in_flight"
+	ErrMsgShutdownFailed            ErrorMessageKey = "shutdown_failed"
+	ErrMsgNotDeadLetter             ErrorMessageKey = "not_dead_letter"
+	ErrMsgLeaseRenewFailed          ErrorMessageKey = "lease_renew_failed"
+	ErrMsgMaxProcessingTime         ErrorMessageKey = "max_processing_time"
+	ErrMsgConsumeFailure            ErrorMessageKey = "consume_failure"
+	ErrMsgListenerPanic             ErrorMessageKey = "listener_panic"
+	ErrMsgPushConsumerStarted       ErrorMessageKey = "push_consumer_started"
+	ErrMsgPushConsumerShutdown      ErrorMessageKey = "push_consumer_shutdown"
+	ErrMsgNoRoute                   ErrorMessageKey = "no_route"
+	ErrMsgInvalidFallbackPolicy     ErrorMessageKey = "invalid_fallback_policy"
+	ErrMsgBatchResultMismatch       ErrorMessageKey = "batch_result_mismatch"
+	ErrMsgDedupFileFailed           ErrorMessageKey = "dedup_file_failed"
+	ErrMsgDedupCompactFailed        ErrorMessageKey = "dedup_compact_failed"
+	ErrMsgDedupStoreClosed          ErrorMessageKey = "dedup_store_closed"
+	ErrMsgInvalidTableName          ErrorMessageKey = "invalid_table_name"
+	ErrMsgOutOfRange                ErrorMessageKey = "out_of_range"
+)
+
+var (
+	errorLangMu   sync.RWMutex
+	errorLang     = "en"
+	errorMessages = map[string]map[ErrorMessageKey]string{
+		"en": {
+			ErrMsgRequired:                  "%s is required",
+			ErrMsgNegative:                  "%s must not be negative, got %v",
+			ErrMsgNonPositive:               "%s must be positive, got %v",
+			ErrMsgInvalidDuration:           "%s %q is not a valid duration, e.g. 5s or 500ms",
+			ErrMsgNonPositiveDuration:       "%s %q must be greater than 0",
+			ErrMsgInvalidInt:                "%s %q is not a valid integer",
+			ErrMsgInvalidBool:               "%s %q is not a valid boolean",
+			ErrMsgInvalidTopicType:          "%s: topic type %q is invalid, supported types are NORMAL, FIFO, DELAY and TRANSACTION",
+			ErrMsgInvalidFilterType:         "%s: filter type %q is invalid, supported types are TAG and SQL92",
+			ErrMsgConfigNodeMissing:         "config node %q is missing or empty",
+			ErrMsgConfigReadFailed:          "failed to read config %s",
+			ErrMsgConfigDecodeFailed:        "failed to decode config of instance %q",
+			ErrMsgInstanceInvalid:           "config of instance %q is invalid",
+			ErrMsgConsumerGroupRequired:     "consumerGroup is required when consumer is configured",
+			ErrMsgFifoGroupRequired:         "messageGroup is required for FIFO messages",
+			ErrMsgDelayTimeRequired:         "deliveryTimestamp is required for DELAY messages",
+			ErrMsgProducerNotStarted:        "producer is not started",
+			ErrMsgMethodNotSupported:        "%s messages are not supported by this method",
+			ErrMsgTopicTypeMismatch:         "topic %s is declared as %s and cannot be sent as %s",
+			ErrMsgThrottled:                 "request was throttled by the broker",
+			ErrMsgCredentialsRetrieveFailed: "failed to retrieve credentials from %s",
+			ErrMsgCredentialsExpired:        "retrieved credentials already expired at %s",
+			ErrMsgTLSPlaintextConflict:      "TLS.Plaintext cannot be combined with other TLS settings",
+			ErrMsgTLSFileInvalid:            "%s %q is not a valid PEM file",
+			ErrMsgTLSConflict:               "address %s is already used by a running client with different TLS settings",
+			ErrMsgInvalidEndpoint:           "endpoint %q is invalid",
+			ErrMsgNoAvailableEndpoint:       "no endpoint is available",
+			ErrMsgTopicNotRegistered:        "topic %s is not registered",
+			ErrMsgTopicsNotCovered:          "registered topics %s are missing from the producer topics",
+			ErrMsgTopicTypeConflict:         "topic %s is registered as %s but declared as %s in the producer options",
+			ErrMsgInvalidFilterExpression:   "%s: %s expression %q is invalid, %s",
+			ErrMsgInvalidPreflightMode:      "%s: preflight mode %q is invalid, supported modes are WARN and FAIL_FAST",
+			ErrMsgPreflightFailed:           "%d of %d preflight checks failed",
+			ErrMsgInvalidAckMode:            "%s: ack mode %q is invalid, supported modes are MANUAL and AUTO",
+			ErrMsgInvalidNackStrategy:       "%s: nack strategy %q is invalid, supported strategies are EXPIRE and BACKOFF",
+			ErrMsgInstanceNotConfigured:     "instance %q is not found or has no %s config",
+			ErrMsgManagerRunning:            "manager is already running",
+			ErrMsgShutdownStepFailed:        "shutdown step %s of instance %q failed",
+			ErrMsgShutdownInFlight:          "%d messages still in flight when the shutdown deadline was reached",
+			ErrMsgShutdownFailed:            "shutdown finished with %d errors",
+			ErrMsgNotDeadLetter:             "message %s is not a client-side dead letter, origin topic property is missing",
+			ErrMsgLeaseRenewFailed:          "failed to renew invisible duration of message %s",
+			ErrMsgMaxProcessingTime:         "message %s was not consumed within max processing time %s",
+			ErrMsgConsumeFailure:            "listener returned failure for message %s",
+			ErrMsgListenerPanic:             "listener panicked on message %s: %v",
+			ErrMsgPushConsumerStarted:       "push consumer is already started",
+			ErrMsgPushConsumerShutdown:      "push consumer is already shut down",
+			ErrMsgNoRoute:                   "no route matches message %s of topic %q with tag %q",
+			ErrMsgInvalidFallbackPolicy:     "%s must be ACK, NACK or DEAD_LETTER, got %q",
+			ErrMsgBatchResultMismatch:       "batch consume func returned %d results for %d messages",
+			ErrMsgDedupFileFailed:           "failed to open dedup file %s",
+			ErrMsgDedupCompactFailed:        "failed to compact dedup file %s, the record was written and compaction will be retried",
+			ErrMsgDedupStoreClosed:          "dedup store %s is closed",
+			ErrMsgInvalidTableName:          "table name %q is invalid, only letters, digits, underscores and one dot are allowed",
+			ErrMsgOutOfRange:                "%s must be between %[3]v and %[4]v, got %[2]v",
+		},
+		"zh": {
+			ErrMsgRequired:                  "%s不能为空",
+			ErrMsgNegative:                  "%s[%v]不能小于0",
+			ErrMsgNonPositive:               "%s[%v]必须大于0",
+			ErrMsgInvalidDuration:           "%s[%s]不是合法的时间长度，如5s、500ms",
+			ErrMsgNonPositiveDuration:       "%s[%s]必须大于0",
+			ErrMsgInvalidInt:                "%s[%s]不是合法的整数",
+			ErrMsgInvalidBool:               "%s[%s]不是合法的布尔值",
+			ErrMsgInvalidTopicType:          "%s的类型[%s]不合法，只支持NORMAL、FIFO、DELAY、TRANSACTION",
+			ErrMsgInvalidFilterType:         "%s的过滤类型[%s]不合法，只支持TAG、SQL92",
+			ErrMsgConfigNodeMissing:         "配置中没有%s节点或节点为空",
+			ErrMsgConfigReadFailed:          "读取配置%s失败",
+			ErrMsgConfigDecodeFailed:        "rocketmq实例[%s]配置解析失败",
+			ErrMsgInstanceInvalid:           "rocketmq实例[%s]配置不合法",
+			ErrMsgConsumerGroupRequired:     "配置了consumer时consumerGroup不能为空",
+			ErrMsgFifoGroupRequired:         "FIFO消息类型messageGroup必填",
+			ErrMsgDelayTimeRequired:         "Delay消息类型deliveryTimestamp必填",
+			ErrMsgProducerNotStarted:        "请先初始化生产者",
+			ErrMsgMethodNotSupported:        "此方法不支持发送%s消息",
+			ErrMsgTopicTypeMismatch:         "主题[%s]的类型为%s，不能按%s类型发送",
+			ErrMsgThrottled:                 "触发了服务端流控",
+			ErrMsgCredentialsRetrieveFailed: "从%s获取凭证失败",
+			ErrMsgCredentialsExpired:        "获取到的凭证已于%s过期",
+			ErrMsgTLSPlaintextConflict:      "TLS.Plaintext不能和其他TLS配置同时设置",
+			ErrMsgTLSFileInvalid:            "%s[%s]不是合法的PEM文件",
+			ErrMsgTLSConflict:               "地址[%s]已被使用不同TLS配置的运行中客户端使用",
+			ErrMsgInvalidEndpoint:           "endpoint[%s]不合法",
+			ErrMsgNoAvailableEndpoint:       "没有可用的endpoint",
+			ErrMsgTopicNotRegistered:        "主题[%s]未注册",
+			ErrMsgTopicsNotCovered:          "已注册的主题[%s]不在生产者的主题列表中",
+			ErrMsgTopicTypeConflict:         "主题[%s]注册的类型为%s，但生产者选项中声明为%s",
+			ErrMsgInvalidFilterExpression:   "%s的%s表达式[%s]不合法，%s",
+			ErrMsgInvalidPreflightMode:      "%s的预检模式[%s]不合法，只支持WARN、FAIL_FAST",
+			ErrMsgPreflightFailed:           "预检不通过，%d/%d项失败",
+			ErrMsgInvalidAckMode:            "%s的确认模式[%s]不合法，只支持MANUAL、AUTO",
+			ErrMsgInvalidNackStrategy:       "%s的消费失败处理方式[%s]不合法，只支持EXPIRE、BACKOFF",
+			ErrMsgInstanceNotConfigured:     "实例[%s]不存在或没有%s配置",
+			ErrMsgManagerRunning:            "客户端管理器已在运行",
+			ErrMsgShutdownStepFailed:        "优雅退出步骤%s失败，实例[%s]",
+			ErrMsgShutdownInFlight:          "优雅退出期限已到，仍有%d条消息未处理完",
+			ErrMsgShutdownFailed:            "优雅退出完成，但有%d个错误",
+			ErrMsgNotDeadLetter:             "消息[%s]不是客户端死信消息，缺少原主题属性",
+			ErrMsgLeaseRenewFailed:          "消息[%s]不可见时间续期失败",
+			ErrMsgMaxProcessingTime:         "消息[%s]未在最长消费时间%s内消费完",
+			ErrMsgConsumeFailure:            "消息[%s]消费失败",
+			ErrMsgListenerPanic:             "消息[%s]的监听方法panic：%v",
+			ErrMsgPushConsumerStarted:       "推送消费者已启动",
+			ErrMsgPushConsumerShutdown:      "推送消费者已注销",
+			ErrMsgNoRoute:                   "消息[%s]没有匹配的路由，主题[%s]，tag[%s]",
+			ErrMsgInvalidFallbackPolicy:     "%s[%s]只能是ACK、NACK或DEAD_LETTER",
+			ErrMsgBatchResultMismatch:       "批量消费方法返回了%d个结果，消息有%d条",
+			ErrMsgDedupFileFailed:           "打开去重文件[%s]失败",
+			ErrMsgDedupCompactFailed:        "压缩去重文件[%s]失败，记录已写入，下次写入时重试压缩",
+			ErrMsgDedupStoreClosed:          "去重存储[%s]已关闭",
+			ErrMsgInvalidTableName:          "表名[%s]不合法，只能包含字母、数字、下划线和一个点",
+			ErrMsgOutOfRange:                "%s[%v]需在%v和%v之间",
+		},
+	}
+)
+
+// SetErrorLanguage 设置错误信息的语言，内置en和zh，默认为en
+// 语言或模板不存在时使用en的模板
+func SetErrorLanguage(lang string) {
+	errorLangMu.Lock()
+	defer errorLangMu.Unlock()
+	errorLang = lang
+}
+
+// RegisterErrorMessages 注册或覆盖某个语言的错误信息模板，模板参数和en的模板一致
+func RegisterErrorMessages(lang string, messages map[ErrorMessageKey]string) {
+	errorLangMu.Lock()
+	defer errorLangMu.Unlock()
+	if errorMessages[lang] == nil {
+		errorMessages[lang] = make(map[ErrorMessageKey]string, len(messages))
+	}
+	for k, v := range messages {
+		errorMessages[lang][k] = v
+	}
+}
+
+func errorMessageTemplate(key ErrorMessageKey) (string, bool) {
+	errorLangMu.RLock()
+	defer errorLangMu.RUnlock()
+	if tpl, ok := errorMessages[errorLang][key]; ok {
+		return tpl, true
+	}
+	tpl, ok := errorMessages["en"][key]
+	return tpl, ok
+}
+
+// Error 本客户端返回的错误，可用errors.As获取错误码，用errors.Is和哨兵错误比较
+type Error struct {
+	Code ErrorCode       //错误码
+	Key  ErrorMessageKey //错误信息模板key，哨兵错误为空
+	Args []any           //错误信息模板参数
+	Err  error           //原始错误，可选
+}
+
+func newError(code ErrorCode, key ErrorMessageKey, args ...any) *Error {
+	return &Error{
+		Code: code,
+		Key:  key,
+		Args: args,
+	}
+}
+
+// wrap 设置原始错误
+func (e *Error) wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// Message 按当前语言生成的错误信息，不包含原始错误
+func (e *Error) Message() string {
+	if e.Key == "" {
+		return string(e.Code)
+	}
+	tpl, ok := errorMessageTemplate(e.Key)
+	if !ok {
+		return fmt.Sprintf("%s%v", e.Key, e.Args)
+	}
+	return fmt.Sprintf(tpl, e.Args...)
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message() + ": " + e.Err.Error()
+	}
+	return e.Message()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误码相同即认为相同，目标设置了Key时还要求Key相同
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && (t.Key == "" || t.Key == e.Key)
+}
+
+// ErrorCodeOf 获取错误链中第一个本客户端错误的错误码，不是本客户端的错误时返回空
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package rocketmq_client
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// useErrorLanguage 切换错误信息语言，测试结束后恢复
+func useErrorLanguage(t *testing.T, lang string) {
+	t.Helper()
+	errorLangMu.RLock()
+	old := errorLang
+	errorLangMu.RUnlock()
+	SetErrorLanguage(lang)
+	t.Cleanup(func() {
+		SetErrorLanguage(old)
+	})
+}
+
+func TestErrorMessageLanguage(t *testing.T) {
+	err := newError(CodeInvalidConfig, ErrMsgRequired, "Endpoint")
+	cases := []struct {
+		lang string
+		want string
+	}{
+		{"en", "Endpoint is required"},
+		{"zh", "Endpoint不能为空"},
+		{"fr", "Endpoint is required"}, //未知语言使用en
+	}
+	for _, c := range cases {
+		useErrorLanguage(t, c.lang)
+		if got := err.Error(); got != c.want {
+			t.Errorf("lang %s: Error() = %q, want %q", c.lang, got, c.want)
+		}
+	}
+
+	//注册的语言缺少模板时使用en
+	RegisterErrorMessages("test", map[ErrorMessageKey]string{ErrMsgRequired: "%s est requis"})
+	t.Cleanup(func() {
+		errorLangMu.Lock()
+		delete(errorMessages, "test")
+		errorLangMu.Unlock()
+	})
+	useErrorLanguage(t, "test")
+	if got := err.Error(); got != "Endpoint est requis" {
+		t.Errorf("registered template = %q", got)
+	}
+	if got := newError(CodeInvalidConfig, ErrMsgDedupStoreClosed, "x").Error(); got != "dedup store x is closed" {
+		t.Errorf("missing key = %q, want en template", got)
+	}
+	//没有模板的key
+	if got := newError(CodeInvalidConfig, "unknown_key", 1).Error(); got != "unknown_key[1]" {
+		t.Errorf("unknown key = %q", got)
+	}
+}
+
+func TestErrorIsAndCode(t *testing.T) {
+	cause := errors.New("cause")
+	err := newError(CodeInvalidConfig, ErrMsgRequired, "Endpoint").wrap(cause)
+	wrapped := fmt.Errorf("start: %w", err)
+
+	if !errors.Is(wrapped, &Error{Code: CodeInvalidConfig}) {
+		t.Error("errors.Is by code through wrapping = false")
+	}
+	if !errors.Is(wrapped, &Error{Code: CodeInvalidConfig, Key: ErrMsgRequired}) {
+		t.Error("errors.Is by code and key = false")
+	}
+	if errors.Is(wrapped, &Error{Code: CodeInvalidConfig, Key: ErrMsgNegative}) {
+		t.Error("errors.Is with another key = true")
+	}
+	if errors.Is(wrapped, &Error{Code: CodeThrottled}) {
+		t.Error("errors.Is with another code = true")
+	}
+	if !errors.Is(wrapped, ErrInvalidConfig) {
+		t.Error("errors.Is sentinel = false")
+	}
+	if !errors.Is(wrapped, cause) {
+		t.Error("errors.Is original error = false")
+	}
+	if got := wrapped.Error(); got != "start: Endpoint is required: cause" {
+		t.Errorf("Error() = %q", got)
+	}
+
+	if code := ErrorCodeOf(wrapped); code != CodeInvalidConfig {
+		t.Errorf("ErrorCodeOf(wrapped) = %q", code)
+	}
+	if code := ErrorCodeOf(cause); code != "" {
+		t.Errorf("ErrorCodeOf(non client error) = %q", code)
+	}
+	if code := ErrorCodeOf(nil); code != "" {
+		t.Errorf("ErrorCodeOf(nil) = %q", code)
+	}
+}
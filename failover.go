@@ -0,0 +1,504 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"github.com/apache/rocketmq-clients/golang/v5/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 切换endpoint的原因
+const (
+	FailoverReasonSendError = "send_error" //发送时遇到可重试的错误
+	FailoverReasonUnhealthy = "unhealthy"  //健康检查不通过
+	FailoverReasonFailback  = "failback"   //优先级更高的endpoint恢复
+)
+
+// healthCheckTimeout 健康检查连接endpoint的超时时间
+var healthCheckTimeout = 3 * time.Second
+
+// FailoverProducer 多endpoint故障切换的生产者
+type FailoverProducer interface {
+	Producer
+	Endpoints() []EndpointStatus //各endpoint的状态，按优先级排列
+}
+
+// EndpointStatus endpoint的状态
+type EndpointStatus struct {
+	Endpoint  string //地址
+	NameSpace string //命名空间
+	Started   bool   //生产者是否已启动
+	Healthy   bool   //最近一次健康检查或发送是否正常
+	Active    bool   //是否是当前使用的endpoint
+	LastError error  //最近一次的错误
+}
+
+// GetFailoverProducer 按优先级依次传入多个集群的配置，获取故障切换的生产者
+// 默认使用第一个可用的endpoint发送，遇到可重试的错误时切到下一个健康的endpoint重新发送，
+// 后台定期检查各endpoint的连通性，优先级更高的endpoint连续FailbackAfter次健康后自动切回。
+// 健康检查只检查TCP能否连通，不发送RPC请求：能建立连接但拒绝请求的集群（如鉴权失败、服务端故障）也会被认为健康并切回，
+// 切回后发送遇到可重试的错误时会再次切走。
+// 启动时连不上的endpoint会在健康检查通过后再启动，所有endpoint都启动失败时返回错误。
+// 注意：事务消息只在服务端确认前切换，切换后由新的endpoint回查事务状态
+func GetFailoverProducer(cfgs []*Config, oFunc ...ProducerOptionFunc) (producer FailoverProducer, err error) {
+	return newFailoverProducer(cfgs, GetProducer, oFunc...)
+}
+
+func newFailoverProducer(cfgs []*Config, getProducer func(cfg *Config, oFunc ...ProducerOptionFunc) (Producer, error), oFunc ...ProducerOptionFunc) (producer *failoverProducer, err error) {
+	if len(cfgs) == 0 {
+		return nil, newError(CodeInvalidConfig, ErrMsgRequired, "cfgs")
+	}
+	for i, cfg := range cfgs {
+		if cfg == nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgRequired, "cfgs["+strconv.Itoa(i)+"]")
+		}
+		if err = checkCfg(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	options := newProducerOptions(oFunc...)
+	if options.HealthCheckInterval <= 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNonPositiveDuration, "HealthCheckInterval", options.HealthCheckInterval.String())
+		return
+	}
+	if options.FailbackAfter < 0 {
+		err = newError(CodeInvalidConfig, ErrMsgNegative, "FailbackAfter", options.FailbackAfter)
+		return
+	}
+
+	producer = &failoverProducer{
+		getProducer: getProducer,
+		oFunc:       oFunc,
+		options:     options,
+		active:      -1,
+	}
+	var errs []error
+	for _, cfg := range cfgs {
+		ep := &failoverEndpoint{cfg: cfg}
+		ep.producer, ep.lastErr = getProducer(cfg, oFunc...)
+		ep.healthy = ep.lastErr == nil
+		if ep.healthy && producer.active < 0 {
+			producer.active = len(producer.endpoints)
+		}
+		if ep.lastErr != nil {
+			errs = append(errs, ep.lastErr)
+		}
+		producer.endpoints = append(producer.endpoints, ep)
+	}
+	if producer.active < 0 {
+		err = newError(CodeProducerNotStarted, ErrMsgNoAvailableEndpoint).wrap(errors.Join(errs...))
+		producer.logEvent(context.Background(), slog.LevelError, opProducerStart, "故障切换生产者启动失败", attrError(err))
+		return nil, err
+	}
+	if producer.active > 0 {
+		producer.logEvent(context.Background(), slog.LevelWarn, opProducerStart, "主endpoint不可用，使用备用endpoint",
+			slog.String(LogKeyEndpoint, cfgs[producer.active].Endpoint),
+		)
+	}
+
+	initMetrics()
+	if producer.metricReg, err = meter.RegisterCallback(producer.observeMetrics, producerEndpointHealthyGauge); err != nil {
+		//指标注册失败不影响发送
+		producer.logEvent(context.Background(), slog.LevelWarn, opProducerStart, "注册endpoint健康指标失败", attrError(err))
+		err = nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	producer.cancel = cancel
+	producer.done = make(chan struct{})
+	go producer.healthCheckLoop(ctx)
+	return
+}
+
+type failoverEndpoint struct {
+	cfg           *Config
+	producer      Producer //为nil表示还没启动成功
+	healthy       bool
+	healthyChecks int //连续健康检查通过的次数
+	lastErr       error
+}
+
+type failoverProducer struct {
+	getProducer func(cfg *Config, oFunc ...ProducerOptionFunc) (Producer, error)
+	oFunc       []ProducerOptionFunc
+	options     *ProducerOptions
+
+	mu        sync.RWMutex
+	endpoints []*failoverEndpoint
+	active    int
+	stopped   bool
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	metricReg metric.Registration
+}
+
+// logEvent 故障切换相关的日志使用第一个配置记录
+func (s *failoverProducer) logEvent(ctx context.Context, level slog.Level, op string, msg string, attrs ...slog.Attr) {
+	logEvent(ctx, s.endpoints[0].cfg, level, op, msg, attrs...)
+}
+
+// Endpoints 各endpoint的状态，按优先级排列
+func (s *failoverProducer) Endpoints() []EndpointStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := make([]EndpointStatus, 0, len(s.endpoints))
+	for i, ep := range s.endpoints {
+		ret = append(ret, EndpointStatus{
+			Endpoint:  ep.cfg.Endpoint,
+			NameSpace: ep.cfg.NameSpace,
+			Started:   ep.producer != nil,
+			Healthy:   ep.healthy,
+			Active:    i == s.active,
+			LastError: ep.lastErr,
+		})
+	}
+	return ret
+}
+
+// Health 故障切换生产者的健康状态，连续错误次数和熔断状态取当前endpoint的，最近成功时间取所有endpoint中最新的
+func (s *failoverProducer) Health() ClientHealth {
+	h := ClientHealth{
+		Kind:      healthKindProducer,
+		Circuit:   CircuitClosed,
+		Endpoints: s.Endpoints(),
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i, ep := range s.endpoints {
+		reporter, ok := ep.producer.(HealthReporter)
+		if !ok {
+			continue
+		}
+		eh := reporter.Health()
+		h.Started = h.Started || (eh.Started && !s.stopped)
+		h.InFlight += eh.InFlight
+		if eh.LastSuccess.After(h.LastSuccess) {
+			h.LastSuccess = eh.LastSuccess
+		}
+		if i == s.active {
+			h.ConsecutiveErrors, h.Circuit, h.CircuitOpenedAt, h.UnhealthySince = eh.ConsecutiveErrors, eh.Circuit, eh.CircuitOpenedAt, eh.UnhealthySince
+		}
+	}
+	return h
+}
+
+// failoverCandidate 一次发送可以尝试的endpoint
+type failoverCandidate struct {
+	index    int
+	producer Producer
+}
+
+// candidates 当前endpoint排在最前，其后按优先级排列其他健康的endpoint
+func (s *failoverProducer) candidates() []failoverCandidate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return nil
+	}
+	ret := make([]failoverCandidate, 0, len(s.endpoints))
+	if ep := s.endpoints[s.active]; ep.producer != nil {
+		ret = append(ret, failoverCandidate{index: s.active, producer: ep.producer})
+	}
+	for i, ep := range s.endpoints {
+		if i != s.active && ep.producer != nil && ep.healthy {
+			ret = append(ret, failoverCandidate{index: i, producer: ep.producer})
+		}
+	}
+	return ret
+}
+
+// markFailure 发送遇到可重试的错误时标记endpoint不健康，是当前endpoint时切到下一个健康的endpoint
+func (s *failoverProducer) markFailure(ctx context.Context, index int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ep := s.endpoints[index]
+	ep.healthy, ep.healthyChecks, ep.lastErr = false, 0, err
+	if index == s.active {
+		s.switchTo(ctx, s.firstHealthy(), FailoverReasonSendError, err)
+	}
+}
+
+// firstHealthy 优先级最高的健康且已启动的endpoint，没有时返回当前endpoint
+func (s *failoverProducer) firstHealthy() int {
+	for i, ep := range s.endpoints {
+		if ep.healthy && ep.producer != nil {
+			return i
+		}
+	}
+	return s.active
+}
+
+// switchTo 切换当前endpoint，调用方需持有写锁
+func (s *failoverProducer) switchTo(ctx context.Context, index int, reason string, err error) {
+	if index == s.active {
+		return
+	}
+	from, to := s.endpoints[s.active].cfg.Endpoint, s.endpoints[index].cfg.Endpoint
+	s.active = index
+	attrs := []slog.Attr{
+		slog.String("from", from),
+		slog.String("to", to),
+		slog.String("reason", reason),
+	}
+	level := slog.LevelWarn
+	if reason == FailoverReasonFailback {
+		level = slog.LevelInfo
+	}
+	if err != nil {
+		attrs = append(attrs, attrError(err))
+	}
+	s.logEvent(ctx, level, opFailover, "生产者切换endpoint", attrs...)
+	producerFailoverCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(MetricKeyFrom, from),
+		attribute.String(MetricKeyTo, to),
+		attribute.String(MetricKeyReason, reason),
+	))
+}
+
+// shouldFailover 错误可重试且调用方没有取消时才切换
+func shouldFailover(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && IsRetryableError(err)
+}
+
+// try 依次在候选endpoint上执行f，直到成功、遇到不可重试的错误或f返回retry为false
+func (s *failoverProducer) try(ctx context.Context, op string, topic string, f func(p Producer) (retry bool, err error)) (err error) {
+	candidates := s.candidates()
+	if len(candidates) == 0 {
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, op, "消息发送失败", attrTopic(topic), attrError(err))
+		return
+	}
+	var retry bool
+	for _, c := range candidates {
+		retry, err = f(c.producer)
+		if !retry || !shouldFailover(ctx, err) {
+			return
+		}
+		s.markFailure(ctx, c.index, err)
+	}
+	return
+}
+
+// Send 同步发送消息，遇到可重试的错误时切换endpoint重新发送
+// 可支持普通、延迟、顺序类型的消息，不支持事务消息
+func (s *failoverProducer) Send(ctx context.Context, topicType TopicType, msg Message) (resp []*rmq_client.SendReceipt, err error) {
+	err = s.try(ctx, opSend, msg.Topic, func(p Producer) (bool, error) {
+		var err error
+		resp, err = p.Send(ctx, topicType, msg)
+		return true, err
+	})
+	return
+}
+
+// SendAsync 异步发送消息，遇到可重试的错误时切换endpoint重新发送，dealFunc只会收到最后一次发送的结果
+// 可支持普通、延迟、顺序类型的消息，不支持事务消息
+func (s *failoverProducer) SendAsync(ctx context.Context, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc) (err error) {
+	candidates := s.candidates()
+	if len(candidates) == 0 {
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	return s.sendAsync(ctx, topicType, msg, dealFunc, candidates)
+}
+
+func (s *failoverProducer) sendAsync(ctx context.Context, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc, candidates []failoverCandidate) error {
+	c := candidates[0]
+	var callback SendAsyncDealFunc
+	if dealFunc != nil {
+		callback = func(ctx context.Context, msg Message, resp []*rmq_client.SendReceipt, err error) {
+			if len(candidates) > 1 && shouldFailover(ctx, err) {
+				s.markFailure(ctx, c.index, err)
+				if err = s.sendAsync(ctx, topicType, msg, dealFunc, candidates[1:]); err == nil {
+					return
+				}
+			}
+			dealFunc(ctx, msg, resp, err)
+		}
+	}
+	return c.producer.SendAsync(ctx, topicType, msg, callback)
+}
+
+// SendTransaction 发送事务消息，只在confirmFunc调用前遇到可重试的错误时切换endpoint重新发送
+// 注意：事务消息的生产者不能和其他类型消息的生产者共用
+func (s *failoverProducer) SendTransaction(ctx context.Context, message Message, confirmFunc ConfirmFunc) (err error) {
+	var confirmed bool
+	wrapped := confirmFunc
+	if confirmFunc != nil {
+		wrapped = func(msg Message, resp []*rmq_client.SendReceipt) bool {
+			confirmed = true
+			return confirmFunc(msg, resp)
+		}
+	}
+	return s.try(ctx, opSendTx, message.Topic, func(p Producer) (bool, error) {
+		err := p.SendTransaction(ctx, message, wrapped)
+		//本地事务已经执行过时，提交或回滚失败也不能重新发送
+		return !confirmed, err
+	})
+}
+
+// Stop 停止健康检查并注销所有endpoint的生产者
+func (s *failoverProducer) Stop() error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.done
+	if s.metricReg != nil {
+		_ = s.metricReg.Unregister()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, ep := range s.endpoints {
+		if ep.producer == nil {
+			continue
+		}
+		if err := ep.producer.Stop(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ep.producer = nil
+	}
+	return errors.Join(errs...)
+}
+
+// healthCheckLoop 定期检查各endpoint，启动之前没启动成功的生产者，并按结果切换或切回
+func (s *failoverProducer) healthCheckLoop(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.options.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.healthCheck(ctx)
+	}
+}
+
+func (s *failoverProducer) healthCheck(ctx context.Context) {
+	s.mu.RLock()
+	endpoints := snapshotEndpoints(s.endpoints)
+	s.mu.RUnlock()
+
+	type result struct {
+		producer Producer
+		err      error
+	}
+	results := make([]result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep failoverEndpoint) {
+			defer wg.Done()
+			if results[i].err = probeEndpoint(ctx, ep.cfg.Endpoint); results[i].err != nil {
+				return
+			}
+			if ep.producer == nil {
+				results[i].producer, results[i].err = s.getProducer(ep.cfg, s.oFunc...)
+			}
+		}(i, ep)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range results {
+		ep := s.endpoints[i]
+		if r.producer != nil {
+			if s.stopped {
+				_ = r.producer.Stop()
+				continue
+			}
+			ep.producer = r.producer
+			s.logEvent(ctx, slog.LevelInfo, opHealthCheck, "endpoint恢复，生产者启动成功", slog.String(LogKeyEndpoint, ep.cfg.Endpoint))
+		}
+		if r.err != nil {
+			if ep.healthy {
+				s.logEvent(ctx, slog.LevelWarn, opHealthCheck, "endpoint健康检查不通过", slog.String(LogKeyEndpoint, ep.cfg.Endpoint), attrError(r.err))
+			}
+			ep.healthy, ep.healthyChecks, ep.lastErr = false, 0, r.err
+			continue
+		}
+		ep.healthy = true
+		ep.healthyChecks++
+	}
+	if s.stopped {
+		return
+	}
+	if !s.endpoints[s.active].healthy {
+		s.switchTo(ctx, s.firstHealthy(), FailoverReasonUnhealthy, s.endpoints[s.active].lastErr)
+		return
+	}
+	for i := 0; i < s.active; i++ {
+		if ep := s.endpoints[i]; ep.healthy && ep.producer != nil && ep.healthyChecks >= s.options.FailbackAfter {
+			s.switchTo(ctx, i, FailoverReasonFailback, nil)
+			return
+		}
+	}
+}
+
+// snapshotEndpoints 复制endpoint的状态，健康检查时不持有锁
+func snapshotEndpoints(endpoints []*failoverEndpoint) []failoverEndpoint {
+	ret := make([]failoverEndpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		ret = append(ret, *ep)
+	}
+	return ret
+}
+
+// probeEndpoint 检查endpoint中是否有可以连通的地址
+func probeEndpoint(ctx context.Context, endpoint string) error {
+	target, err := utils.ParseTarget(endpoint)
+	if err != nil {
+		return newError(CodeInvalidConfig, ErrMsgInvalidEndpoint, endpoint).wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	var (
+		dialer net.Dialer
+		errs   []error
+	)
+	for _, address := range target.GetAddresses() {
+		conn, err := dialer.DialContext(ctx, "tcp", utils.ParseAddress(address))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_ = conn.Close()
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// observeMetrics 上报各endpoint的健康状态
+func (s *failoverProducer) observeMetrics(_ context.Context, o metric.Observer) error {
+	for _, st := range s.Endpoints() {
+		var v int64
+		if st.Healthy {
+			v = 1
+		}
+		o.ObserveInt64(producerEndpointHealthyGauge, v, metric.WithAttributes(
+			attribute.String(MetricKeyEndpoint, st.Endpoint),
+			attribute.Bool(MetricKeyActive, st.Active),
+		))
+	}
+	return nil
+}
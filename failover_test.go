@@ -0,0 +1,192 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeProducer struct {
+	mu      sync.Mutex
+	sendErr error
+	sends   int
+	stopped bool
+}
+
+func (p *fakeProducer) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sendErr = err
+}
+
+func (p *fakeProducer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sends
+}
+
+func (p *fakeProducer) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	return nil
+}
+
+func (p *fakeProducer) Send(context.Context, TopicType, Message) ([]*rmq_client.SendReceipt, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sends++
+	if p.sendErr != nil {
+		return nil, p.sendErr
+	}
+	return []*rmq_client.SendReceipt{{MessageID: "id"}}, nil
+}
+
+func (p *fakeProducer) SendAsync(ctx context.Context, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc) error {
+	resp, err := p.Send(ctx, topicType, msg)
+	dealFunc(ctx, msg, resp, err)
+	return nil
+}
+
+func (p *fakeProducer) SendTransaction(ctx context.Context, msg Message, confirmFunc ConfirmFunc) error {
+	resp, err := p.Send(ctx, TopicTransaction, msg)
+	if err != nil {
+		return err
+	}
+	confirmFunc(msg, resp)
+	return nil
+}
+
+// listenEndpoint 返回可以连通的地址和关闭它的方法
+func listenEndpoint(t *testing.T) (string, func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return l.Addr().String(), func() {
+		_ = l.Close()
+	}
+}
+
+func newTestFailoverProducer(t *testing.T, endpoints ...string) (*failoverProducer, map[string]*fakeProducer) {
+	t.Helper()
+	var cfgs []*Config
+	producers := map[string]*fakeProducer{}
+	for _, ep := range endpoints {
+		cfgs = append(cfgs, &Config{Endpoint: ep, NameSpace: "test"})
+		producers[ep] = &fakeProducer{}
+	}
+	var mu sync.Mutex
+	p, err := newFailoverProducer(cfgs, func(cfg *Config, _ ...ProducerOptionFunc) (Producer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return producers[cfg.Endpoint], nil
+	}, WithProducerOptionHealthCheckInterval(time.Hour), WithProducerOptionFailbackAfter(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = p.Stop()
+	})
+	return p, producers
+}
+
+func activeEndpoint(p *failoverProducer) string {
+	for _, st := range p.Endpoints() {
+		if st.Active {
+			return st.Endpoint
+		}
+	}
+	return ""
+}
+
+func TestFailoverSend(t *testing.T) {
+	p, producers := newTestFailoverProducer(t, "primary:8081", "backup:8081")
+	msg := Message{Topic: "t", Body: "b"}
+
+	//不可重试的错误不切换
+	producers["primary:8081"].setErr(brokerError(v2.Code_MESSAGE_BODY_TOO_LARGE))
+	if _, err := p.Send(context.Background(), TopicNormal, msg); !IsMessageTooLarge(err) {
+		t.Fatalf("err = %v", err)
+	}
+	if activeEndpoint(p) != "primary:8081" || producers["backup:8081"].count() != 0 {
+		t.Fatal("switched on non retryable error")
+	}
+
+	//可重试的错误切到备用endpoint重新发送
+	producers["primary:8081"].setErr(brokerError(v2.Code_TOO_MANY_REQUESTS))
+	if _, err := p.Send(context.Background(), TopicNormal, msg); err != nil {
+		t.Fatalf("failover send err = %v", err)
+	}
+	if activeEndpoint(p) != "backup:8081" || producers["backup:8081"].count() != 1 {
+		t.Fatalf("active = %s", activeEndpoint(p))
+	}
+
+	//无法识别的错误不切换
+	producers["backup:8081"].setErr(errors.New("unknown"))
+	if _, err := p.Send(context.Background(), TopicNormal, msg); err == nil {
+		t.Fatal("expected error")
+	}
+	if activeEndpoint(p) != "backup:8081" {
+		t.Fatal("switched on unknown error")
+	}
+}
+
+func TestFailoverHealthCheckAndFailback(t *testing.T) {
+	oldTimeout := healthCheckTimeout
+	healthCheckTimeout = 200 * time.Millisecond
+	defer func() {
+		healthCheckTimeout = oldTimeout
+	}()
+	primary, closePrimary := listenEndpoint(t)
+	backup, closeBackup := listenEndpoint(t)
+	defer closeBackup()
+	p, _ := newTestFailoverProducer(t, primary, backup)
+	ctx := context.Background()
+
+	//主endpoint连不上时切到备用endpoint
+	closePrimary()
+	p.healthCheck(ctx)
+	if activeEndpoint(p) != backup {
+		t.Fatalf("active = %s, want backup", activeEndpoint(p))
+	}
+
+	//主endpoint恢复后连续FailbackAfter次健康才切回
+	l, err := net.Listen("tcp", primary)
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %v", primary, err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	p.healthCheck(ctx)
+	if activeEndpoint(p) != backup {
+		t.Fatal("failed back before FailbackAfter checks")
+	}
+	p.healthCheck(ctx)
+	if activeEndpoint(p) != primary {
+		t.Fatalf("active = %s, want primary after failback", activeEndpoint(p))
+	}
+}
@@ -0,0 +1,378 @@
+package rocketmq_client
+
+import (
+	"fmt"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"strconv"
+	"strings"
+)
+
+// checkFilterExpression 在本地校验订阅表达式的语法，TAG表达式为*或用||分隔的tag，SQL92表达式按服务端支持的语法校验
+// 只在预检时执行，本地校验和服务端不一致时不影响不开启预检的客户端启动
+func checkFilterExpression(topic string, fe *FilterExpression) error {
+	if fe == nil {
+		return newError(CodeInvalidConfig, ErrMsgRequired, "SubExpressions."+topic)
+	}
+	var detail string
+	switch fe.ExpressionType {
+	case rmq_client.TAG:
+		detail = checkTagExpression(fe.Expression)
+		if detail != "" {
+			return newError(CodeInvalidConfig, ErrMsgInvalidFilterExpression, topic, "TAG", fe.Expression, detail)
+		}
+	case rmq_client.SQL92:
+		detail = checkSql92Expression(fe.Expression)
+		if detail != "" {
+			return newError(CodeInvalidConfig, ErrMsgInvalidFilterExpression, topic, "SQL92", fe.Expression, detail)
+		}
+	default:
+		return newError(CodeInvalidConfig, ErrMsgInvalidFilterType, topic, strconv.Itoa(int(fe.ExpressionType)))
+	}
+	return nil
+}
+
+// checkTagExpression 校验TAG表达式，返回错误详情，合法时返回空
+func checkTagExpression(expression string) string {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return "empty expression"
+	}
+	if expression == "*" {
+		return ""
+	}
+	for i, tag := range strings.Split(expression, "||") {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Sprintf("tag %d is empty", i+1)
+		}
+	}
+	return ""
+}
+
+// checkSql92Expression 校验SQL92表达式，返回错误详情，合法时返回空
+// 支持的语法：AND、OR、NOT、括号、=、<>、>、>=、<、<=、[NOT] BETWEEN ... AND ...、[NOT] IN (...)、IS [NOT] NULL、
+// [NOT] CONTAINS、[NOT] STARTSWITH、[NOT] ENDSWITH，常量为字符串（单引号）、数字（含1L、0x1F、1.5e3）、TRUE、FALSE
+func checkSql92Expression(expression string) string {
+	tokens, detail := sqlTokenize(expression)
+	if detail != "" {
+		return detail
+	}
+	if len(tokens) == 0 {
+		return "empty expression"
+	}
+	p := &sqlParser{tokens: tokens}
+	if detail = p.parseOr(); detail != "" {
+		return detail
+	}
+	if t := p.peek(); t.kind != sqlEOF {
+		return p.unexpected(t)
+	}
+	return ""
+}
+
+type sqlTokenKind int
+
+const (
+	sqlEOF sqlTokenKind = iota
+	sqlIdent
+	sqlKeyword
+	sqlString
+	sqlNumber
+	sqlOperator
+	sqlLParen
+	sqlRParen
+	sqlComma
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string //关键字为大写
+	pos  int
+}
+
+var sqlKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "BETWEEN": true, "IN": true, "IS": true, "NULL": true,
+	"TRUE": true, "FALSE": true, "CONTAINS": true, "STARTSWITH": true, "ENDSWITH": true,
+}
+
+func sqlTokenize(s string) ([]sqlToken, string) {
+	var tokens []sqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, sqlToken{kind: sqlLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, sqlToken{kind: sqlRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, sqlToken{kind: sqlComma, text: ",", pos: i})
+			i++
+		case c == '=':
+			tokens = append(tokens, sqlToken{kind: sqlOperator, text: "=", pos: i})
+			i++
+		case c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(s) && (s[i+1] == '=' || (c == '<' && s[i+1] == '>')) {
+				op += string(s[i+1])
+			}
+			tokens = append(tokens, sqlToken{kind: sqlOperator, text: op, pos: i})
+			i += len(op)
+		case c == '\'':
+			start := i
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Sprintf("position %d: unterminated string", start)
+				}
+				if s[i] == '\'' {
+					//两个单引号表示转义
+					if i+1 < len(s) && s[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlString, text: s[start:i], pos: start})
+		case c == '-' || c == '+' || c == '.' || isDigit(c):
+			start := i
+			if i = scanSqlNumber(s, i); i < 0 {
+				end := start + 1
+				for end < len(s) && (isIdentStart(s[end]) || isDigit(s[end]) || s[end] == '.') {
+					end++
+				}
+				return nil, fmt.Sprintf("position %d: invalid number %q", start, s[start:end])
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: s[start:i], pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(s) && (isIdentStart(s[i]) || isDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			word := s[start:i]
+			if upper := strings.ToUpper(word); sqlKeywords[upper] {
+				tokens = append(tokens, sqlToken{kind: sqlKeyword, text: upper, pos: start})
+			} else {
+				tokens = append(tokens, sqlToken{kind: sqlIdent, text: word, pos: start})
+			}
+		default:
+			return nil, fmt.Sprintf("position %d: unexpected character %q", i, c)
+		}
+	}
+	return tokens, ""
+}
+
+// scanSqlNumber 扫描从i开始的数字常量，返回结束位置，不合法时返回-1
+// 支持可选的正负号、十进制整数（可带l/L后缀）、0x开头的十六进制整数、小数和e/E指数
+func scanSqlNumber(s string, i int) int {
+	if s[i] == '-' || s[i] == '+' {
+		i++
+	}
+	if i+1 < len(s) && s[i] == '0' && (s[i+1] == 'x' || s[i+1] == 'X') {
+		start := i + 2
+		for i = start; i < len(s) && isHexDigit(s[i]); i++ {
+		}
+		if i == start || (i < len(s) && (isIdentStart(s[i]) || s[i] == '.')) {
+			return -1
+		}
+		return i
+	}
+	digits, dots := 0, 0
+	for ; i < len(s) && (isDigit(s[i]) || s[i] == '.'); i++ {
+		if s[i] == '.' {
+			dots++
+		} else {
+			digits++
+		}
+	}
+	if digits == 0 || dots > 1 {
+		return -1
+	}
+	switch {
+	case i < len(s) && (s[i] == 'e' || s[i] == 'E'):
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		start := i
+		for ; i < len(s) && isDigit(s[i]); i++ {
+		}
+		if i == start {
+			return -1
+		}
+	case i < len(s) && (s[i] == 'l' || s[i] == 'L') && dots == 0:
+		i++
+	}
+	//数字后面不能直接跟标识符字符，如1a
+	if i < len(s) && (isIdentStart(s[i]) || s[i] == '.') {
+		return -1
+	}
+	return i
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// sqlParser 递归下降校验，各方法返回错误详情，合法时返回空
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+}
+
+func (p *sqlParser) peek() sqlToken {
+	if p.pos >= len(p.tokens) {
+		return sqlToken{kind: sqlEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.peek()
+	if t.kind != sqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sqlParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == sqlKeyword && t.text == keyword
+}
+
+func (p *sqlParser) unexpected(t sqlToken) string {
+	if t.kind == sqlEOF {
+		return "unexpected end of expression"
+	}
+	return fmt.Sprintf("position %d: unexpected %q", t.pos, t.text)
+}
+
+func (p *sqlParser) parseOr() string {
+	if detail := p.parseAnd(); detail != "" {
+		return detail
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		if detail := p.parseAnd(); detail != "" {
+			return detail
+		}
+	}
+	return ""
+}
+
+func (p *sqlParser) parseAnd() string {
+	if detail := p.parseNot(); detail != "" {
+		return detail
+	}
+	for p.isKeyword("AND") {
+		p.next()
+		if detail := p.parseNot(); detail != "" {
+			return detail
+		}
+	}
+	return ""
+}
+
+func (p *sqlParser) parseNot() string {
+	if p.isKeyword("NOT") {
+		p.next()
+		return p.parseNot()
+	}
+	return p.parsePredicate()
+}
+
+func (p *sqlParser) parsePredicate() string {
+	t := p.peek()
+	if t.kind == sqlLParen {
+		p.next()
+		if detail := p.parseOr(); detail != "" {
+			return detail
+		}
+		if t = p.next(); t.kind != sqlRParen {
+			return p.unexpected(t)
+		}
+		return ""
+	}
+	if t.kind == sqlKeyword && (t.text == "TRUE" || t.text == "FALSE") {
+		p.next()
+		return ""
+	}
+	if detail := p.parseOperand(); detail != "" {
+		return detail
+	}
+
+	t = p.next()
+	switch {
+	case t.kind == sqlOperator:
+		return p.parseOperand()
+	case t.kind == sqlKeyword && t.text == "IS":
+		if p.isKeyword("NOT") {
+			p.next()
+		}
+		if t = p.next(); t.kind != sqlKeyword || t.text != "NULL" {
+			return p.unexpected(t)
+		}
+		return ""
+	case t.kind == sqlKeyword && t.text == "NOT":
+		t = p.next()
+		if t.kind != sqlKeyword {
+			return p.unexpected(t)
+		}
+		fallthrough
+	case t.kind == sqlKeyword:
+		switch t.text {
+		case "BETWEEN":
+			if detail := p.parseOperand(); detail != "" {
+				return detail
+			}
+			if t = p.next(); t.kind != sqlKeyword || t.text != "AND" {
+				return p.unexpected(t)
+			}
+			return p.parseOperand()
+		case "IN":
+			if t = p.next(); t.kind != sqlLParen {
+				return p.unexpected(t)
+			}
+			for {
+				if t = p.next(); t.kind != sqlString && t.kind != sqlNumber {
+					return p.unexpected(t)
+				}
+				if t = p.next(); t.kind == sqlRParen {
+					return ""
+				} else if t.kind != sqlComma {
+					return p.unexpected(t)
+				}
+			}
+		case "CONTAINS", "STARTSWITH", "ENDSWITH":
+			if t = p.next(); t.kind != sqlString {
+				return p.unexpected(t)
+			}
+			return ""
+		}
+	}
+	return p.unexpected(t)
+}
+
+func (p *sqlParser) parseOperand() string {
+	t := p.next()
+	switch {
+	case t.kind == sqlIdent, t.kind == sqlString, t.kind == sqlNumber:
+		return ""
+	case t.kind == sqlKeyword && (t.text == "TRUE" || t.text == "FALSE" || t.text == "NULL"):
+		return ""
+	}
+	return p.unexpected(t)
+}
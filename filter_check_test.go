@@ -0,0 +1,110 @@
+package rocketmq_client
+
+import (
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"testing"
+)
+
+func TestCheckSql92Expression(t *testing.T) {
+	cases := []struct {
+		expression string
+		valid      bool
+	}{
+		{"a = 1", true},
+		{"a = 'x' AND b <> 2", true},
+		{"a = 1.5e3", true},
+		{"a = 1.5E-3", true},
+		{"a = 2e10", true},
+		{"a = 10L", true},
+		{"a = 10l", true},
+		{"a = 0x1F", true},
+		{"a = 0XaB", true},
+		{"a > -1 OR a < +.5", true},
+		{"a BETWEEN 1 AND 10", true},
+		{"a NOT BETWEEN 1L AND 0x10", true},
+		{"a IN ('x', 'y')", true},
+		{"a NOT IN ('x')", true},
+		{"a IS NOT NULL", true},
+		{"a IS NULL AND b = TRUE", true},
+		{"a CONTAINS 'x' OR b NOT STARTSWITH 'y' OR c ENDSWITH 'z'", true},
+		{"NOT (a = 1 OR (b >= 2 AND c <= 3))", true},
+		{"", false},
+		{"a =", false},
+		{"a = 1.5e", false},
+		{"a = 1.5L", false},
+		{"a = 0x", false},
+		{"a = 0x1G", false},
+		{"a = 1.2.3", false},
+		{"a = 10LL", false},
+		{"a = 1a", false},
+		{"a = 'x", false},
+		{"(a = 1", false},
+		{"a BETWEEN 1", false},
+		{"a IN ()", false},
+		{"a IS 1", false},
+		{"a = 1 b = 2", false},
+	}
+	for _, c := range cases {
+		detail := checkSql92Expression(c.expression)
+		if (detail == "") != c.valid {
+			t.Errorf("checkSql92Expression(%q) = %q, want valid=%v", c.expression, detail, c.valid)
+		}
+	}
+}
+
+func TestCheckTagExpression(t *testing.T) {
+	cases := []struct {
+		expression string
+		valid      bool
+	}{
+		{"*", true},
+		{"tagA", true},
+		{"tagA || tagB", true},
+		{" tagA||tagB ", true},
+		{"", false},
+		{"  ", false},
+		{"tagA ||", false},
+		{"|| tagA", false},
+		{"tagA |||| tagB", false},
+	}
+	for _, c := range cases {
+		detail := checkTagExpression(c.expression)
+		if (detail == "") != c.valid {
+			t.Errorf("checkTagExpression(%q) = %q, want valid=%v", c.expression, detail, c.valid)
+		}
+	}
+}
+
+func TestCheckFilterExpression(t *testing.T) {
+	if err := checkFilterExpression("t", nil); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("nil expression: %v", err)
+	}
+	if err := checkFilterExpression("t", NewFilterExpression("tagA||tagB")); err != nil {
+		t.Fatalf("tag expression: %v", err)
+	}
+	if err := checkFilterExpression("t", NewFilterExpressionWithType("a = 0x1F", rmq_client.SQL92)); err != nil {
+		t.Fatalf("sql92 expression: %v", err)
+	}
+	if err := checkFilterExpression("t", NewFilterExpressionWithType("a = ", rmq_client.SQL92)); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("bad sql92 expression: %v", err)
+	}
+	if err := checkFilterExpression("t", NewFilterExpressionWithType("a", rmq_client.FilterExpressionType(99))); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("bad type: %v", err)
+	}
+}
+
+// 订阅表达式语法只在预检时校验，加载配置时不校验
+func TestValidateSkipsFilterSyntax(t *testing.T) {
+	cfgs, err := LoadConfigContent([]byte(testConfigYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := cfgs["default"]
+	for _, sub := range cfg.Consumer.Subscriptions {
+		sub.Type = "SQL92"
+		sub.Expression = "a = = 1"
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Fatalf("Validate checked filter syntax: %v", err)
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"errors"
 	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
 	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gogf/gf/v2/net/gtrace"
+	"github.com/gogf/gf/v2/os/gcfg"
 	"github.com/gogf/gf/v2/util/gconv"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"time"
 )
 
@@ -25,6 +28,16 @@ func GetGfProducer(cfg *Config, oFunc ...ProducerOptionFunc) (producer Producer,
 	return
 }
 
+// GetGfFailoverProducer gf版故障切换的生产者，每个endpoint的生产者都会记录链路追踪
+func GetGfFailoverProducer(cfgs []*Config, oFunc ...ProducerOptionFunc) (producer FailoverProducer, err error) {
+	return newFailoverProducer(cfgs, GetGfProducer, oFunc...)
+}
+
+// GetGfTopicProducer gf版按主题注册表发送的生产者，见GetTopicProducer
+func GetGfTopicProducer(cfg *Config, registry *TopicRegistry, oFunc ...ProducerOptionFunc) (producer TopicProducer, err error) {
+	return getTopicProducer(cfg, registry, GetGfProducer, oFunc...)
+}
+
 type defaultGfProducer struct {
 	*defaultProducer
 }
@@ -33,13 +46,19 @@ type defaultGfProducer struct {
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func (s *defaultGfProducer) Send(ctx context.Context, topicType TopicType, msg Message) (resp []*rmq_client.SendReceipt, err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	if err = s.checkTopicType(msg.Topic, topicType); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 	//记录链路追踪span
 	ctx, endFunc := addSendTrace(ctx, &msg)
+	done := s.track()
 	resp, err = Send(ctx, s.Cfg, s.producer, topicType, msg)
+	done(err)
 	endFunc(resp, err)
 	return
 }
@@ -48,16 +67,25 @@ func (s *defaultGfProducer) Send(ctx context.Context, topicType TopicType, msg M
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func (s *defaultGfProducer) SendAsync(ctx context.Context, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc) (err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	if err = s.checkTopicType(msg.Topic, topicType); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 	//记录链路追踪span
 	ctx, endFunc := addSendTrace(ctx, &msg)
+	done := s.track()
 	err = SendAsync(ctx, s.Cfg, s.producer, topicType, msg, func(ctx context.Context, msg Message, resp []*rmq_client.SendReceipt, err error) {
+		done(err)
 		endFunc(resp, err)
 		dealFunc(ctx, msg, resp, err)
 	})
+	if err != nil {
+		done(err)
+	}
 	return
 }
 
@@ -65,13 +93,19 @@ func (s *defaultGfProducer) SendAsync(ctx context.Context, topicType TopicType,
 // 注意：事务消息的生产者不能和其他类型消息的生产者共用
 func (s *defaultGfProducer) SendTransaction(ctx context.Context, msg Message, confirmFunc ConfirmFunc) (err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSendTx, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	if err = s.checkTopicType(msg.Topic, TopicTransaction); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSendTx, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 	//记录链路追踪span
 	ctx, endFunc := addSendTrace(ctx, &msg)
+	done := s.track()
 	resp, err := SendTransaction(ctx, s.Cfg, s.producer, msg, confirmFunc)
+	done(err)
 	endFunc(resp, err)
 	return
 }
@@ -111,9 +145,50 @@ func addSendTrace(ctx context.Context, msg *Message) (context.Context, func(resp
 	}
 }
 
+// BindHealthRoutes4Gf 在gf路由分组上注册健康检查路由：GET /health（完整状态）、/health/live（存活）、/health/ready（就绪）
+func BindHealthRoutes4Gf(group *ghttp.RouterGroup, checker *HealthChecker) {
+	group.GET("/health", ghttp.WrapH(checker))
+	group.GET("/health/live", ghttp.WrapH(checker.LivenessHandler()))
+	group.GET("/health/ready", ghttp.WrapH(checker.ReadinessHandler()))
+}
+
+// LoadConfig4Gf 从gf配置中加载所有实例配置，pattern为配置节点，默认为rocketmq
+// 节点下的结构和LoadConfigFile的配置文件相同
+func LoadConfig4Gf(ctx context.Context, pattern ...string) (map[string]*InstanceConfig, error) {
+	node := ConfigNodeName
+	if len(pattern) > 0 && pattern[0] != "" {
+		node = pattern[0]
+	}
+	v, err := gcfg.Instance().Get(ctx, node)
+	if err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgConfigReadFailed, "gcfg:"+node).wrap(err)
+	}
+	return decodeInstanceConfigs(v.Map())
+}
+
+// MultiClusterConsume4Gf gf版多集群消费，见MultiClusterConsume
+func MultiClusterConsume4Gf(ctx context.Context, cfgs map[string]*Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	return multiClusterConsume(ctx, cfgs, SimpleConsume4Gf, consumeFunc, oFunc...)
+}
+
+// StartSimpleConsumer4Gf gf版启动简单消费者，见StartSimpleConsumer
+func StartSimpleConsumer4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (SimpleConsumer, error) {
+	return StartSimpleConsumer(ctx, cfg, gfConsumeFunc(cfg, consumeFunc), oFunc...)
+}
+
+// NewPushConsumer4Gf gf版推送消费者，见NewPushConsumer
+func NewPushConsumer4Gf(cfg *Config, listener MessageListener, oFunc ...ConsumerOptionFunc) (PushConsumer, error) {
+	return newPushConsumer(cfg, listener, StartSimpleConsumer4Gf, oFunc...)
+}
+
 // SimpleConsume4Gf gf版简单消费类型消费
-func SimpleConsume4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func(), err error) {
-	return SimpleConsume(ctx, cfg, func(ctx context.Context, msg *rmq_client.MessageView, consumer Consumer) error {
+func SimpleConsume4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	return SimpleConsume(ctx, cfg, gfConsumeFunc(cfg, consumeFunc), oFunc...)
+}
+
+// gfConsumeFunc 包装消费方法，按消息中的链路信息记录链路追踪span
+func gfConsumeFunc(cfg *Config, consumeFunc ConsumeFunc) ConsumeFunc {
+	return func(ctx context.Context, msg *rmq_client.MessageView, consumer Consumer) error {
 		var (
 			err  error
 			span *gtrace.Span
@@ -128,7 +203,13 @@ func SimpleConsume4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc,
 			spanContext.TraceID, errTranceId = trace.TraceIDFromHex(v)
 			spanContext.SpanID, errSpanId = trace.SpanIDFromHex(spanIdStr)
 			if errTranceId != nil || errSpanId != nil {
-				debugLog(cfg, "span context failed traceId[%s] spanId[%s] TraceError:%v spanError:%v", v, spanIdStr, errTranceId, errSpanId)
+				logEvent(ctx, cfg, slog.LevelWarn, opTrace, "解析链路信息失败",
+					attrTopic(msg.GetTopic()),
+					attrMessageId(msg.GetMessageId()),
+					slog.String("trace_id", v),
+					slog.String("span_id", spanIdStr),
+					attrError(errors.Join(errTranceId, errSpanId)),
+				)
 			} else {
 				ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(spanContext))
 				ctx, span = gtrace.NewSpan(ctx, "rocketmqConsume")
@@ -143,7 +224,11 @@ func SimpleConsume4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc,
 				)
 			}
 		} else {
-			debugLog(cfg, "message[%s]无traceInfo:%+v", msg.GetMessageId(), msg.GetProperties())
+			logEvent(ctx, cfg, slog.LevelDebug, opTrace, "消息无链路信息",
+				attrTopic(msg.GetTopic()),
+				attrMessageId(msg.GetMessageId()),
+				slog.Any("properties", msg.GetProperties()),
+			)
 		}
 
 		err = consumeFunc(ctx, msg, consumer)
@@ -157,6 +242,6 @@ func SimpleConsume4Gf(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc,
 			span.SetAttributes(attribute.String("endTime", time.Now().Format("2006-01-02 15:04:05.999")))
 			span.End()
 		}
-		return nil
-	}, oFunc...)
+		return err
+	}
 }
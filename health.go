@@ -0,0 +1,244 @@
+package rocketmq_client
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CircuitState 健康熔断状态，由连续错误次数决定，熔断打开时就绪检查不通过
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    //正常
+	CircuitOpen     CircuitState = "open"      //连续错误次数达到阈值
+	CircuitHalfOpen CircuitState = "half_open" //熔断打开超过冷却时间，等待下一次请求的结果
+)
+
+// 健康熔断的阈值
+var (
+	circuitErrorThreshold int64 = 5                //连续错误多少次后打开
+	circuitCooldown             = 30 * time.Second //打开多久后转为半开
+)
+
+// ClientHealth 生产者或消费者的健康状态
+type ClientHealth struct {
+	Name              string           `json:"name"`                //注册的名称
+	Kind              string           `json:"kind"`                //producer或consumer
+	Started           bool             `json:"started"`             //是否已启动且未注销
+	LastSuccess       time.Time        `json:"lastSuccess"`         //最近一次发送或接收成功的时间
+	ConsecutiveErrors int64            `json:"consecutiveErrors"`   //连续错误次数
+	Circuit           CircuitState     `json:"circuit"`             //熔断状态
+	CircuitOpenedAt   time.Time        `json:"circuitOpenedAt"`     //熔断打开的时间，未打开时为零，半开后再次打开时更新
+	UnhealthySince    time.Time        `json:"unhealthySince"`      //熔断从关闭转为打开的时间，半开后再次打开时不变，恢复后为零
+	InFlight          int64            `json:"inFlight"`            //正在发送或消费的消息数
+	Endpoints         []EndpointStatus `json:"endpoints,omitempty"` //故障切换生产者的各endpoint状态
+}
+
+// MarshalJSON LastError输出为错误信息
+func (s EndpointStatus) MarshalJSON() ([]byte, error) {
+	var lastError string
+	if s.LastError != nil {
+		lastError = s.LastError.Error()
+	}
+	return json.Marshal(struct {
+		Endpoint  string `json:"endpoint"`
+		NameSpace string `json:"nameSpace"`
+		Started   bool   `json:"started"`
+		Healthy   bool   `json:"healthy"`
+		Active    bool   `json:"active"`
+		LastError string `json:"lastError,omitempty"`
+	}{s.Endpoint, s.NameSpace, s.Started, s.Healthy, s.Active, lastError})
+}
+
+// HealthReporter 可以报告健康状态的客户端，本客户端的生产者都实现了此接口
+type HealthReporter interface {
+	Health() ClientHealth
+}
+
+const (
+	healthKindProducer = "producer"
+	healthKindConsumer = "consumer"
+)
+
+// clientStats 记录客户端的健康统计，并发安全
+type clientStats struct {
+	kind              string
+	started           atomic.Bool
+	lastSuccess       atomic.Int64 //UnixNano
+	consecutiveErrors atomic.Int64
+	inFlight          atomic.Int64
+
+	mu             sync.Mutex
+	circuit        CircuitState
+	openedAt       time.Time
+	unhealthySince time.Time //从关闭转为打开的时间，用于存活检查
+}
+
+func newClientStats(kind string) *clientStats {
+	s := &clientStats{kind: kind, circuit: CircuitClosed}
+	s.started.Store(true)
+	return s
+}
+
+// begin 开始发送或消费一条消息
+func (s *clientStats) begin() {
+	s.inFlight.Add(1)
+}
+
+// end 结束发送或消费一条消息
+func (s *clientStats) end() {
+	s.inFlight.Add(-1)
+}
+
+// record 记录一次请求的结果，本客户端校验不通过的错误不计入
+func (s *clientStats) record(err error) {
+	if err != nil && ClassifyError(err) == ErrorClassInvalidClientRequest {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.lastSuccess.Store(time.Now().UnixNano())
+		s.consecutiveErrors.Store(0)
+		s.circuit, s.openedAt, s.unhealthySince = CircuitClosed, time.Time{}, time.Time{}
+		return
+	}
+	n := s.consecutiveErrors.Add(1)
+	switch {
+	case s.circuit == CircuitHalfOpen:
+		//半开时探测失败，重新打开，不重置不健康的起始时间
+		s.circuit, s.openedAt = CircuitOpen, time.Now()
+	case s.circuit == CircuitClosed && n >= circuitErrorThreshold:
+		now := time.Now()
+		s.circuit, s.openedAt, s.unhealthySince = CircuitOpen, now, now
+	}
+}
+
+// health 生成健康状态，熔断打开超过冷却时间时转为半开
+func (s *clientStats) health(name string) ClientHealth {
+	s.mu.Lock()
+	if s.circuit == CircuitOpen && time.Since(s.openedAt) >= circuitCooldown {
+		s.circuit = CircuitHalfOpen
+	}
+	h := ClientHealth{
+		Name:              name,
+		Kind:              s.kind,
+		Started:           s.started.Load(),
+		ConsecutiveErrors: s.consecutiveErrors.Load(),
+		Circuit:           s.circuit,
+		CircuitOpenedAt:   s.openedAt,
+		UnhealthySince:    s.unhealthySince,
+		InFlight:          s.inFlight.Load(),
+	}
+	s.mu.Unlock()
+	if v := s.lastSuccess.Load(); v > 0 {
+		h.LastSuccess = time.Unix(0, v)
+	}
+	return h
+}
+
+// statsReporter 把clientStats包装为HealthReporter，用于没有句柄的消费者
+type statsReporter struct {
+	stats *clientStats
+}
+
+func (r statsReporter) Health() ClientHealth {
+	return r.stats.health("")
+}
+
+// HealthChecker 汇总注册的生产者和消费者的健康状态，提供存活和就绪检查的http.Handler
+// 就绪：所有客户端都已启动，且熔断未打开；存活：没有客户端从熔断打开起持续不健康超过FailureTimeout，期间半开探测失败不重新计时
+type HealthChecker struct {
+	FailureTimeout time.Duration //从熔断打开起持续不健康多久后存活检查不通过，默认5分钟
+
+	mu      sync.RWMutex
+	clients map[string]HealthReporter
+}
+
+// NewHealthChecker 创建健康检查
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		FailureTimeout: 5 * time.Minute,
+		clients:        map[string]HealthReporter{},
+	}
+}
+
+// Register 注册生产者或消费者，同名的会被覆盖；生产者可直接注册，消费者通过WithConsumerOptionHealth注册
+func (h *HealthChecker) Register(name string, reporter HealthReporter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[name] = reporter
+}
+
+// Unregister 取消注册
+func (h *HealthChecker) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, name)
+}
+
+// HealthReport 健康检查的结果
+type HealthReport struct {
+	Live    bool           `json:"live"`
+	Ready   bool           `json:"ready"`
+	Clients []ClientHealth `json:"clients"`
+}
+
+// Report 获取所有注册客户端的健康状态，按名称排序
+func (h *HealthChecker) Report() HealthReport {
+	h.mu.RLock()
+	clients := make([]ClientHealth, 0, len(h.clients))
+	for name, reporter := range h.clients {
+		c := reporter.Health()
+		c.Name = name
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Name < clients[j].Name
+	})
+
+	report := HealthReport{Live: true, Ready: true, Clients: clients}
+	for _, c := range clients {
+		if !c.Started || c.Circuit == CircuitOpen {
+			report.Ready = false
+		}
+		if c.Circuit != CircuitClosed && !c.UnhealthySince.IsZero() && time.Since(c.UnhealthySince) >= h.FailureTimeout {
+			report.Live = false
+		}
+	}
+	return report
+}
+
+// ServeHTTP 返回完整的健康状态，就绪时状态码为200，否则为503
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Report()
+	writeHealthReport(w, report, report.Ready)
+}
+
+// LivenessHandler 存活检查，可用于Kubernetes的livenessProbe
+func (h *HealthChecker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report()
+		writeHealthReport(w, report, report.Live)
+	})
+}
+
+// ReadinessHandler 就绪检查，可用于Kubernetes的readinessProbe
+func (h *HealthChecker) ReadinessHandler() http.Handler {
+	return h
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
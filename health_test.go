@@ -0,0 +1,143 @@
+package rocketmq_client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useCircuitCooldown 修改熔断冷却时间，测试结束后恢复
+func useCircuitCooldown(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := circuitCooldown
+	circuitCooldown = d
+	t.Cleanup(func() {
+		circuitCooldown = old
+	})
+}
+
+func TestClientStatsCircuit(t *testing.T) {
+	useCircuitCooldown(t, 20*time.Millisecond)
+	s := newClientStats(healthKindProducer)
+	sendErr := brokerError(503)
+	for i := int64(1); i < circuitErrorThreshold; i++ {
+		s.record(sendErr)
+	}
+	//本客户端校验不通过的错误不计入
+	s.record(newError(CodeInvalidMessage, ErrMsgRequired, "Body"))
+	if h := s.health(""); h.Circuit != CircuitClosed || h.ConsecutiveErrors != circuitErrorThreshold-1 {
+		t.Fatalf("before threshold: %+v", h)
+	}
+	s.record(sendErr)
+	h := s.health("")
+	if h.Circuit != CircuitOpen || h.CircuitOpenedAt.IsZero() || !h.UnhealthySince.Equal(h.CircuitOpenedAt) {
+		t.Fatalf("at threshold: %+v", h)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if h = s.health(""); h.Circuit != CircuitHalfOpen {
+		t.Fatalf("after cooldown circuit = %s, want half open", h.Circuit)
+	}
+	//半开时失败重新打开，不健康的起始时间不变
+	unhealthySince := h.UnhealthySince
+	s.record(sendErr)
+	if h = s.health(""); h.Circuit != CircuitOpen || !h.UnhealthySince.Equal(unhealthySince) || !h.CircuitOpenedAt.After(unhealthySince) {
+		t.Fatalf("reopened: %+v", h)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	s.health("")
+	s.record(nil)
+	if h = s.health(""); h.Circuit != CircuitClosed || h.ConsecutiveErrors != 0 || !h.UnhealthySince.IsZero() || h.LastSuccess.IsZero() {
+		t.Fatalf("after success: %+v", h)
+	}
+}
+
+func TestHealthCheckerLivenessWithHalfOpenCycles(t *testing.T) {
+	useCircuitCooldown(t, 10*time.Millisecond)
+	s := newClientStats(healthKindConsumer)
+	checker := NewHealthChecker()
+	checker.FailureTimeout = 100 * time.Millisecond
+	checker.Register("c", statsReporter{stats: s})
+
+	for i := int64(0); i < circuitErrorThreshold; i++ {
+		s.record(errors.New("receive failed"))
+	}
+	//探测一直失败：每次冷却后转为半开，再失败重新打开
+	deadline := time.Now().Add(150 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if report := checker.Report(); !report.Live && time.Now().Add(50*time.Millisecond).Before(deadline) {
+			t.Fatal("liveness failed before FailureTimeout")
+		}
+		time.Sleep(15 * time.Millisecond)
+		s.record(errors.New("receive failed"))
+	}
+	if h := s.health(""); time.Since(h.CircuitOpenedAt) >= checker.FailureTimeout {
+		t.Fatalf("circuit was not reopened by probes: %+v", h)
+	}
+	if report := checker.Report(); report.Live || report.Ready {
+		t.Fatalf("report after FailureTimeout = %+v, want not live and not ready", report)
+	}
+
+	s.record(nil)
+	if report := checker.Report(); !report.Live || !report.Ready {
+		t.Fatalf("report after success = %+v", report)
+	}
+}
+
+func TestHealthCheckerHandlers(t *testing.T) {
+	checker := NewHealthChecker()
+	ok := newClientStats(healthKindProducer)
+	stopped := newClientStats(healthKindConsumer)
+	checker.Register("b_producer", statsReporter{stats: ok})
+	checker.Register("a_consumer", statsReporter{stats: stopped})
+
+	get := func(h http.Handler) (int, HealthReport) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		var report HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("body %q: %v", w.Body.String(), err)
+		}
+		return w.Code, report
+	}
+
+	code, report := get(checker.ReadinessHandler())
+	if code != http.StatusOK || !report.Ready || len(report.Clients) != 2 || report.Clients[0].Name != "a_consumer" || report.Clients[1].Kind != healthKindProducer {
+		t.Fatalf("readiness = %d %+v", code, report)
+	}
+
+	//未启动的客户端就绪检查不通过，存活检查仍通过
+	stopped.started.Store(false)
+	if code, report = get(checker.ReadinessHandler()); code != http.StatusServiceUnavailable || report.Ready {
+		t.Fatalf("readiness with stopped client = %d %+v", code, report)
+	}
+	if code, report = get(checker.LivenessHandler()); code != http.StatusOK || !report.Live {
+		t.Fatalf("liveness with stopped client = %d %+v", code, report)
+	}
+
+	//不健康超过FailureTimeout后存活检查不通过
+	for i := int64(0); i < circuitErrorThreshold; i++ {
+		ok.record(errors.New("send failed"))
+	}
+	ok.mu.Lock()
+	ok.unhealthySince = time.Now().Add(-checker.FailureTimeout)
+	ok.mu.Unlock()
+	if code, report = get(checker.LivenessHandler()); code != http.StatusServiceUnavailable || report.Live {
+		t.Fatalf("liveness with failing client = %d %+v", code, report)
+	}
+	if report.Clients[1].Circuit != CircuitOpen || report.Clients[1].ConsecutiveErrors != circuitErrorThreshold {
+		t.Fatalf("client health in body = %+v", report.Clients[1])
+	}
+
+	checker.Unregister("b_producer")
+	if report = checker.Report(); len(report.Clients) != 1 {
+		t.Fatalf("clients after Unregister = %+v", report.Clients)
+	}
+}
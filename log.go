@@ -1,20 +1,159 @@
 package rocketmq_client
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 )
 
+// 结构化日志的属性名
+const (
+	LogKeyOperation     = "operation"      //操作，如send、receive、ack
+	LogKeyTopic         = "topic"          //主题
+	LogKeyMessageId     = "message_id"     //消息ID
+	LogKeyConsumerGroup = "consumer_group" //消费者分组
+	LogKeyError         = "error"          //错误信息
+	LogKeyErrorCode     = "error_code"     //错误码，本客户端的错误才有
+	LogKeyErrorClass    = "error_class"    //错误分类，见ClassifyError
+	LogKeyEndpoint      = "endpoint"       //服务端地址
+)
+
+// 日志中的操作名
+const (
+	opProducerStart = "producer.start"
+	opProducerStop  = "producer.stop"
+	opSend          = "send"
+	opSendAsync     = "send.async"
+	opSendTx        = "send.transaction"
+	opMessageInit   = "message.init"
+	opConsumerStart = "consumer.start"
+	opConsumerStop  = "consumer.stop"
+	opReceive       = "receive"
+	opConsume       = "consume"
+	opTrace         = "trace"
+	opCredentials   = "credentials"
+	opFailover      = "producer.failover"
+	opHealthCheck   = "producer.health_check"
+	opAck           = "ack"
+	opNack          = "nack"
+	opManager       = "manager"
+	opDeadLetter    = "dead_letter"
+	opLease         = "lease"
+	opDedup         = "dedup"
+)
+
 type debugHandlerFunc func(msg string)
 
-func debugLog(cfg *Config, format string, args ...any) {
-	msg := fmt.Sprintf(format, args...)
-	if cfg.DebugHandlerFunc != nil {
-		cfg.DebugHandlerFunc(msg)
+// getLogger 获取配置对应的日志记录器
+// 配置了Logger时直接使用，否则使用Debug、DebugHandlerFunc的适配器
+func getLogger(cfg *Config) *slog.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return slog.New(&debugHandler{cfg: cfg})
+}
+
+// logEvent 记录一条结构化日志，配置了ConsumerGroup时会自动附带consumer_group属性
+func logEvent(ctx context.Context, cfg *Config, level slog.Level, op string, msg string, attrs ...slog.Attr) {
+	attrs = append([]slog.Attr{slog.String(LogKeyOperation, op)}, attrs...)
+	if cfg.ConsumerGroup != "" {
+		attrs = append(attrs, slog.String(LogKeyConsumerGroup, cfg.ConsumerGroup))
+	}
+	for _, a := range attrs {
+		if err, ok := a.Value.Any().(error); ok && a.Key == LogKeyError {
+			if code := ErrorCodeOf(err); code != "" {
+				attrs = append(attrs, slog.String(LogKeyErrorCode, string(code)))
+			}
+			attrs = append(attrs, slog.String(LogKeyErrorClass, string(ClassifyError(err))))
+			break
+		}
+	}
+	getLogger(cfg).LogAttrs(ctx, level, msg, attrs...)
+}
+
+func attrTopic(topic string) slog.Attr {
+	return slog.String(LogKeyTopic, topic)
+}
+
+func attrMessageId(messageId string) slog.Attr {
+	return slog.String(LogKeyMessageId, messageId)
+}
+
+func attrError(err error) slog.Attr {
+	return slog.Any(LogKeyError, err)
+}
+
+// debugHandler 把结构化日志适配到Debug和DebugHandlerFunc
+type debugHandler struct {
+	cfg    *Config
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *debugHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	//DebugHandlerFunc不管debug开没开都会调用，所以所有级别都启用
+	return h.cfg.DebugHandlerFunc != nil || h.cfg.Debug
+}
+
+func (h *debugHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&b, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+	msg := b.String()
+	if h.cfg.DebugHandlerFunc != nil {
+		h.cfg.DebugHandlerFunc(msg)
+	}
+	if !h.cfg.Debug {
+		return nil
+	}
+	fmt.Printf("%s %10s %s\n", r.Time.Format("2006-01-02 15:04:05.000"), r.Level.String(), msg)
+	return nil
+}
+
+func (h *debugHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	n.attrs = append(n.attrs, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		n.attrs = append(n.attrs, a)
 	}
-	if !cfg.Debug {
+	return &n
+}
+
+func (h *debugHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	n := *h
+	n.prefix = h.prefix + name + "."
+	return &n
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix+a.Key+".", ga)
+		}
 		return
 	}
-	fmt.Printf("%s %10s %s\n", time.Now().Format("2006-01-02 15:04:05.000"), "DEBUG", msg)
-	return
+	var v string
+	if a.Value.Kind() == slog.KindTime {
+		v = a.Value.Time().Format(time.DateTime)
+	} else {
+		v = a.Value.String()
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, v)
 }
@@ -0,0 +1,105 @@
+package rocketmq_client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout 返回f执行期间输出到终端的内容
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	_ = w.Close()
+	b, _ := io.ReadAll(r)
+	return string(b)
+}
+
+func TestLogEventDebugHandlerFunc(t *testing.T) {
+	err := newError(CodeInvalidConfig, ErrMsgRequired, "Endpoint")
+	cases := []struct {
+		name       string
+		debug      bool
+		handler    bool
+		wantFunc   bool
+		wantStdout bool
+	}{
+		{"debug and func", true, true, true, true},
+		{"func only", false, true, true, false},
+		{"debug only", true, false, false, true},
+		{"neither", false, false, false, false},
+	}
+	for _, c := range cases {
+		var lines []string
+		cfg := &Config{Debug: c.debug, ConsumerGroup: "cg"}
+		if c.handler {
+			//DebugHandlerFunc不管Debug开没开都会调用
+			cfg.DebugHandlerFunc = func(msg string) {
+				lines = append(lines, msg)
+			}
+		}
+		out := captureStdout(t, func() {
+			logEvent(context.Background(), cfg, slog.LevelDebug, opSend, "消息发送失败", attrTopic("t"), attrError(err))
+		})
+		if got := len(lines) > 0; got != c.wantFunc {
+			t.Errorf("%s: DebugHandlerFunc called = %v, want %v", c.name, got, c.wantFunc)
+		}
+		if got := out != ""; got != c.wantStdout {
+			t.Errorf("%s: stdout = %q, want output %v", c.name, out, c.wantStdout)
+		}
+		for _, line := range append(lines, out) {
+			if line == "" {
+				continue
+			}
+			for _, want := range []string{"消息发送失败", "operation=send", "topic=t", "consumer_group=cg", "error_code=INVALID_CONFIG"} {
+				if !strings.Contains(line, want) {
+					t.Errorf("%s: line %q does not contain %q", c.name, line, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLogEventCustomHandler(t *testing.T) {
+	h := &recordHandler{}
+	err := newError(CodeInvalidConfig, ErrMsgRequired, "Endpoint")
+	//配置了Logger后不再调用DebugHandlerFunc
+	cfg := &Config{Logger: slog.New(h), DebugHandlerFunc: func(string) {
+		t.Error("DebugHandlerFunc called with Logger set")
+	}}
+	logEvent(context.Background(), cfg, slog.LevelError, opAck, "确认消息失败", attrMessageId("m1"), attrError(err))
+
+	if len(h.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(h.records))
+	}
+	r := h.records[0]
+	if r.Level != slog.LevelError || r.Message != "确认消息失败" {
+		t.Fatalf("record = %v %q", r.Level, r.Message)
+	}
+	attrs := map[string]slog.Value{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+	if attrs[LogKeyOperation].String() != opAck || attrs[LogKeyMessageId].String() != "m1" {
+		t.Errorf("attrs = %v", attrs)
+	}
+	if got, _ := attrs[LogKeyError].Any().(error); got != err {
+		t.Errorf("error attr = %v, want the original error", attrs[LogKeyError])
+	}
+	if attrs[LogKeyErrorCode].String() != string(CodeInvalidConfig) || attrs[LogKeyErrorClass].String() == "" {
+		t.Errorf("error code and class attrs = %v", attrs)
+	}
+}
@@ -0,0 +1,349 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 优雅退出的步骤名，用于日志和错误信息
+const (
+	ShutdownStepDrainHandlers = "drain_handlers" //等待正在执行的消费方法返回
+	ShutdownStepStopConsumer  = "stop_consumer"  //注销消费者
+	ShutdownStepFlushAsync    = "flush_async"    //等待异步发送完成
+	ShutdownStepStopProducer  = "stop_producer"  //注销生产者
+)
+
+// flushPollInterval 等待异步发送完成时检查的间隔
+var flushPollInterval = 50 * time.Millisecond
+
+type ManagerOptionFunc func(options *ManagerOptions)
+
+type ManagerOptions struct {
+	ShutdownTimeout time.Duration  //优雅退出的期限，默认30秒
+	Signals         []os.Signal    //触发优雅退出的信号，默认SIGINT、SIGTERM，为空时也使用默认值
+	Gf              bool           //是否使用gf版的生产者和消费者，会记录链路追踪
+	HealthChecker   *HealthChecker //健康检查，可选，设置后所有生产者和消费者按实例名注册
+	Logger          *slog.Logger   //管理器自身的日志记录器，可选，各实例的日志仍按实例配置记录
+}
+
+func WithManagerOptionShutdownTimeout(shutdownTimeout time.Duration) ManagerOptionFunc {
+	return func(o *ManagerOptions) {
+		o.ShutdownTimeout = shutdownTimeout
+	}
+}
+
+// WithManagerOptionSignals 触发优雅退出的信号，不传时使用默认的SIGINT、SIGTERM，避免监听所有信号
+func WithManagerOptionSignals(signals ...os.Signal) ManagerOptionFunc {
+	return func(o *ManagerOptions) {
+		o.Signals = signals
+	}
+}
+
+func WithManagerOptionGf(gf bool) ManagerOptionFunc {
+	return func(o *ManagerOptions) {
+		o.Gf = gf
+	}
+}
+
+func WithManagerOptionHealthChecker(checker *HealthChecker) ManagerOptionFunc {
+	return func(o *ManagerOptions) {
+		o.HealthChecker = checker
+	}
+}
+
+func WithManagerOptionLogger(logger *slog.Logger) ManagerOptionFunc {
+	return func(o *ManagerOptions) {
+		o.Logger = logger
+	}
+}
+
+// Manager 管理按实例配置创建的所有生产者和消费者，负责启动和收到退出信号后的优雅退出
+type Manager struct {
+	instances map[string]*InstanceConfig
+	options   *ManagerOptions
+	logCfg    *Config
+
+	mu        sync.Mutex
+	names     []string //配置了producer的实例名，按名称排序
+	producers map[string]Producer
+	consumers []*managedConsumer
+	running   bool
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+	closing      chan struct{} //开始优雅退出时关闭
+}
+
+type managedConsumer struct {
+	name        string
+	cfg         *Config
+	consumeFunc ConsumeFunc
+	oFunc       []ConsumerOptionFunc
+	consumer    *simpleConsumer //Run之后才有
+}
+
+func defaultManagerSignals() []os.Signal {
+	return []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+}
+
+// NewManager 按实例配置创建管理器，并启动所有配置了producer的实例的生产者，任一生产者启动失败时注销已启动的生产者并返回错误
+// 实例配置可通过LoadConfigFile、LoadConfigEnv或LoadConfig4Gf加载
+func NewManager(instances map[string]*InstanceConfig, oFunc ...ManagerOptionFunc) (m *Manager, err error) {
+	options := &ManagerOptions{
+		ShutdownTimeout: 30 * time.Second,
+		Signals:         defaultManagerSignals(),
+	}
+	for _, f := range oFunc {
+		f(options)
+	}
+	//signal.NotifyContext不传信号时会监听所有信号
+	if len(options.Signals) == 0 {
+		options.Signals = defaultManagerSignals()
+	}
+	m = &Manager{
+		instances: instances,
+		options:   options,
+		logCfg:    &Config{Logger: options.Logger},
+		producers: map[string]Producer{},
+		closing:   make(chan struct{}),
+	}
+
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	getProducer := GetProducer
+	if options.Gf {
+		getProducer = GetGfProducer
+	}
+	for _, name := range names {
+		ic := instances[name]
+		if ic.Producer == nil {
+			continue
+		}
+		p, err := getProducer(ic.Config(), ic.ProducerOptionFuncs()...)
+		if err != nil {
+			for _, started := range m.names {
+				_ = m.producers[started].Stop()
+			}
+			return nil, err
+		}
+		m.names = append(m.names, name)
+		m.producers[name] = p
+		if options.HealthChecker != nil {
+			if reporter, ok := p.(HealthReporter); ok {
+				options.HealthChecker.Register(name, reporter)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Producer 获取实例的生产者，实例没有配置producer时返回错误
+func (m *Manager) Producer(name string) (Producer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.producers[name]
+	if !ok {
+		return nil, newError(CodeInvalidArgument, ErrMsgInstanceNotConfigured, name, "producer")
+	}
+	return p, nil
+}
+
+// Consumers 获取实例已启动的消费者，按Consume的调用顺序，Run之前为空，可用于运行中调整并发数
+func (m *Manager) Consumers(name string) []SimpleConsumer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ret []SimpleConsumer
+	for _, mc := range m.consumers {
+		if mc.name == name && mc.consumer != nil {
+			ret = append(ret, mc.consumer)
+		}
+	}
+	return ret
+}
+
+// Consume 声明实例的消费方法，Run时启动，实例需配置consumer，oFunc在实例配置的选项之后生效
+func (m *Manager) Consume(name string, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) error {
+	ic, ok := m.instances[name]
+	if !ok || ic.Consumer == nil {
+		return newError(CodeInvalidArgument, ErrMsgInstanceNotConfigured, name, "consumer")
+	}
+	if consumeFunc == nil {
+		return newError(CodeInvalidArgument, ErrMsgRequired, "consumeFunc")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return newError(CodeInvalidArgument, ErrMsgManagerRunning)
+	}
+	if m.options.Gf {
+		consumeFunc = gfConsumeFunc(ic.Config(), consumeFunc)
+	}
+	oFuncs := append(ic.ConsumerOptionFuncs(), oFunc...)
+	if m.options.HealthChecker != nil {
+		oFuncs = append(oFuncs, WithConsumerOptionHealth(m.options.HealthChecker, name))
+	}
+	m.consumers = append(m.consumers, &managedConsumer{
+		name:        name,
+		cfg:         ic.Config(),
+		consumeFunc: consumeFunc,
+		oFunc:       oFuncs,
+	})
+	return nil
+}
+
+// Run 启动所有声明的消费者，阻塞到ctx结束、收到退出信号或调用了Shutdown，然后优雅退出
+// 消费者启动失败时立即优雅退出，返回启动和退出的错误；正常退出时返回Shutdown的错误
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return newError(CodeInvalidArgument, ErrMsgManagerRunning)
+	}
+	m.running = true
+	consumers := m.consumers
+	m.mu.Unlock()
+
+	//消费者不跟随ctx取消，退出时按顺序停止
+	consumeCtx := context.WithoutCancel(ctx)
+	for _, mc := range consumers {
+		c, err := startSimpleConsumer(consumeCtx, mc.cfg, mc.consumeFunc, mc.oFunc...)
+		if err != nil {
+			return errors.Join(err, m.Shutdown(context.Background()))
+		}
+		m.mu.Lock()
+		mc.consumer = c
+		m.mu.Unlock()
+	}
+	logEvent(ctx, m.logCfg, slog.LevelInfo, opManager, "客户端管理器已启动",
+		slog.Int("producers", len(m.names)),
+		slog.Int("consumers", len(consumers)),
+	)
+
+	signalCtx, stop := signal.NotifyContext(ctx, m.options.Signals...)
+	defer stop()
+	select {
+	case <-signalCtx.Done():
+		logEvent(ctx, m.logCfg, slog.LevelInfo, opManager, "开始优雅退出", attrError(context.Cause(signalCtx)))
+	case <-m.closing:
+	}
+	return m.Shutdown(context.Background())
+}
+
+// Shutdown 按顺序优雅退出：停止所有消费者接收消息、等待正在执行的消费方法返回、注销消费者、等待异步发送完成、注销生产者
+// ctx没有期限时使用ShutdownTimeout作为期限；期限到达后仍会注销所有客户端，返回汇总了各步骤错误的错误
+// 只会执行一次，重复调用返回第一次的结果
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.shutdownOnce.Do(func() {
+		close(m.closing)
+		m.shutdownErr = m.shutdown(ctx)
+	})
+	return m.shutdownErr
+}
+
+func (m *Manager) shutdown(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok && m.options.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.options.ShutdownTimeout)
+		defer cancel()
+	}
+
+	m.mu.Lock()
+	var consumers []*managedConsumer
+	for _, mc := range m.consumers {
+		if mc.consumer != nil {
+			consumers = append(consumers, mc)
+		}
+	}
+	producers := make(map[string]Producer, len(m.producers))
+	for name, p := range m.producers {
+		producers[name] = p
+	}
+	m.mu.Unlock()
+
+	var (
+		errsMu sync.Mutex
+		errs   []error
+	)
+	addErr := func(step, name string, err error) {
+		logEvent(ctx, m.logCfg, slog.LevelError, opManager, "优雅退出步骤失败", slog.String("step", step), slog.String("instance", name), attrError(err))
+		errsMu.Lock()
+		defer errsMu.Unlock()
+		errs = append(errs, newError(CodeShutdownFailed, ErrMsgShutdownStepFailed, step, name).wrap(err))
+	}
+
+	for _, mc := range consumers {
+		mc.consumer.stopReceiving()
+	}
+	parallel(consumers, func(mc *managedConsumer) {
+		if err := mc.consumer.wait(ctx); err != nil {
+			addErr(ShutdownStepDrainHandlers, mc.name, newError(CodeShutdownFailed, ErrMsgShutdownInFlight, mc.consumer.stats.inFlight.Load()).wrap(err))
+		}
+		if err := mc.consumer.shutdown(); err != nil {
+			addErr(ShutdownStepStopConsumer, mc.name, err)
+		}
+	})
+
+	names := make([]string, 0, len(producers))
+	for name := range producers {
+		names = append(names, name)
+	}
+	parallel(names, func(name string) {
+		p := producers[name]
+		if err := waitAsyncSends(ctx, p); err != nil {
+			addErr(ShutdownStepFlushAsync, name, err)
+		}
+		if err := p.Stop(); err != nil {
+			addErr(ShutdownStepStopProducer, name, err)
+		}
+	})
+
+	if len(errs) > 0 {
+		return newError(CodeShutdownFailed, ErrMsgShutdownFailed, len(errs)).wrap(errors.Join(errs...))
+	}
+	logEvent(ctx, m.logCfg, slog.LevelInfo, opManager, "优雅退出完成")
+	return nil
+}
+
+// waitAsyncSends 等待生产者正在进行的发送完成，生产者没有实现HealthReporter时不等待
+func waitAsyncSends(ctx context.Context, p Producer) error {
+	reporter, ok := p.(HealthReporter)
+	if !ok {
+		return nil
+	}
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for {
+		n := reporter.Health().InFlight
+		if n <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return newError(CodeShutdownFailed, ErrMsgShutdownInFlight, n).wrap(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// parallel 并发对每个元素执行f，等待全部完成
+func parallel[T any](items []T, f func(T)) {
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		go func(item T) {
+			defer wg.Done()
+			f(item)
+		}(item)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,253 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestManagerOptionSignals(t *testing.T) {
+	cases := map[string]struct {
+		oFunc []ManagerOptionFunc
+		want  []os.Signal
+	}{
+		"default": {nil, []os.Signal{syscall.SIGINT, syscall.SIGTERM}},
+		"empty":   {[]ManagerOptionFunc{WithManagerOptionSignals()}, []os.Signal{syscall.SIGINT, syscall.SIGTERM}},
+		"custom":  {[]ManagerOptionFunc{WithManagerOptionSignals(syscall.SIGHUP)}, []os.Signal{syscall.SIGHUP}},
+	}
+	for name, c := range cases {
+		m, err := NewManager(map[string]*InstanceConfig{}, c.oFunc...)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !slices.Equal(m.options.Signals, c.want) {
+			t.Errorf("%s: signals = %v, want %v", name, m.options.Signals, c.want)
+		}
+	}
+}
+
+// shutdownEvents 按发生顺序记录优雅退出的各步骤
+type shutdownEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *shutdownEvents) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *shutdownEvents) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.events)
+}
+
+// shutdownProducer 记录注销的生产者，inFlight为还没完成的异步发送数，每次检查减一，stuck时不减少
+type shutdownProducer struct {
+	fakeProducer
+	events   *shutdownEvents
+	name     string
+	inFlight atomic.Int64
+	stuck    bool
+	stopErr  error
+}
+
+func (p *shutdownProducer) Health() ClientHealth {
+	n := p.inFlight.Load()
+	if n > 0 && !p.stuck {
+		p.inFlight.Add(-1)
+	}
+	if n == 0 {
+		p.events.add(ShutdownStepFlushAsync + ":" + p.name)
+	}
+	return ClientHealth{InFlight: n}
+}
+
+func (p *shutdownProducer) Stop() error {
+	p.events.add(ShutdownStepStopProducer + ":" + p.name)
+	return p.stopErr
+}
+
+// shutdownSdkConsumer 记录官方客户端消费者的注销
+type shutdownSdkConsumer struct {
+	rmq_client.SimpleConsumer
+	events  *shutdownEvents
+	name    string
+	stopErr error
+}
+
+func (c *shutdownSdkConsumer) GracefulStop() error {
+	c.events.add(ShutdownStepStopConsumer + ":" + c.name)
+	return c.stopErr
+}
+
+// newShutdownConsumer 构造不运行接收循环的消费者，停止接收后模拟正在执行的消费方法，stuck时消费方法到测试结束才返回
+func newShutdownConsumer(t *testing.T, events *shutdownEvents, name string, stuck bool, stopErr error) *managedConsumer {
+	s := newTestSimpleConsumer(&ConsumerOptions{})
+	s.consumer = &shutdownSdkConsumer{events: events, name: name, stopErr: stopErr}
+	s.stopCredentials = func() {}
+	s.stats.inFlight.Store(1)
+	release := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+	})
+	go func() {
+		<-s.stopping
+		events.add("stop_receiving:" + name)
+		if stuck {
+			<-release
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		s.stats.inFlight.Store(0)
+		events.add(ShutdownStepDrainHandlers + ":" + name)
+		close(s.loopDone)
+	}()
+	return &managedConsumer{name: name, cfg: s.cfg, consumer: s}
+}
+
+func newShutdownManager(t *testing.T, consumers []*managedConsumer, producers map[string]Producer) *Manager {
+	t.Helper()
+	m, err := NewManager(map[string]*InstanceConfig{}, WithManagerOptionShutdownTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.consumers = consumers
+	for name, p := range producers {
+		m.names = append(m.names, name)
+		m.producers[name] = p
+	}
+	return m
+}
+
+func TestManagerShutdownOrder(t *testing.T) {
+	oldInterval := flushPollInterval
+	flushPollInterval = 5 * time.Millisecond
+	defer func() {
+		flushPollInterval = oldInterval
+	}()
+	events := &shutdownEvents{}
+	p := &shutdownProducer{events: events, name: "p"}
+	p.inFlight.Store(3)
+	m := newShutdownManager(t,
+		[]*managedConsumer{newShutdownConsumer(t, events, "c", false, nil)},
+		map[string]Producer{"p": p},
+	)
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown err = %v", err)
+	}
+	want := []string{"stop_receiving:c", "drain_handlers:c", "stop_consumer:c", "flush_async:p", "stop_producer:p"}
+	if got := events.list(); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	//重复调用返回第一次的结果，不会再次注销
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second shutdown err = %v", err)
+	}
+	if got := events.list(); len(got) != len(want) {
+		t.Fatalf("events after second shutdown = %v", got)
+	}
+}
+
+func TestManagerShutdownDeadline(t *testing.T) {
+	events := &shutdownEvents{}
+	p := &shutdownProducer{events: events, name: "p", stuck: true}
+	p.inFlight.Store(1)
+	m := newShutdownManager(t,
+		[]*managedConsumer{newShutdownConsumer(t, events, "c", true, nil)},
+		map[string]Producer{"p": p},
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := m.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("shutdown took %v, want it to stop waiting at the deadline", elapsed)
+	}
+	if ErrorCodeOf(err) != CodeShutdownFailed || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a shutdown timeout error", err)
+	}
+	for _, step := range []string{ShutdownStepDrainHandlers, ShutdownStepFlushAsync} {
+		if !strings.Contains(err.Error(), step) {
+			t.Errorf("err = %v, want step %s", err, step)
+		}
+	}
+	//期限到达后仍注销所有客户端
+	got := events.list()
+	for _, event := range []string{"stop_consumer:c", "stop_producer:p"} {
+		if !slices.Contains(got, event) {
+			t.Errorf("events = %v, want %s", got, event)
+		}
+	}
+}
+
+func TestManagerShutdownJoinErrors(t *testing.T) {
+	events := &shutdownEvents{}
+	errC1, errC2 := errors.New("consumer c1 stop failed"), errors.New("consumer c2 stop failed")
+	errP1, errP2 := errors.New("producer p1 stop failed"), errors.New("producer p2 stop failed")
+	m := newShutdownManager(t,
+		[]*managedConsumer{
+			newShutdownConsumer(t, events, "c1", false, errC1),
+			newShutdownConsumer(t, events, "c2", false, errC2),
+			newShutdownConsumer(t, events, "c3", false, nil),
+		},
+		map[string]Producer{
+			"p1": &shutdownProducer{events: events, name: "p1", stopErr: errP1},
+			"p2": &shutdownProducer{events: events, name: "p2", stopErr: errP2},
+			"p3": &shutdownProducer{events: events, name: "p3"},
+		},
+	)
+	err := m.Shutdown(context.Background())
+	if ErrorCodeOf(err) != CodeShutdownFailed {
+		t.Fatalf("err = %v, want %s", err, CodeShutdownFailed)
+	}
+	for _, want := range []error{errC1, errC2, errP1, errP2} {
+		if !errors.Is(err, want) {
+			t.Errorf("err = %v, want it to contain %v", err, want)
+		}
+	}
+	if !strings.Contains(err.Error(), "4") {
+		t.Errorf("err = %v, want the error count", err)
+	}
+}
+
+// countingSdkProducer 记录官方客户端生产者的注销次数
+type countingSdkProducer struct {
+	rmq_client.Producer
+	stops atomic.Int32
+}
+
+func (p *countingSdkProducer) GracefulStop() error {
+	p.stops.Add(1)
+	return nil
+}
+
+func TestManagerShutdownStopsProducerOnce(t *testing.T) {
+	sdk := &countingSdkProducer{}
+	p := &defaultProducer{Cfg: &Config{}, producer: sdk, stats: newClientStats(healthKindProducer)}
+	m := newShutdownManager(t, nil, map[string]Producer{"p": p})
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown err = %v", err)
+	}
+	//已调用过Shutdown，Run立即退出，不会再次注销
+	if err := m.Run(context.Background()); err != nil {
+		t.Fatalf("run err = %v", err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("second stop err = %v", err)
+	}
+	if n := sdk.stops.Load(); n != 1 {
+		t.Fatalf("GracefulStop called %d times, want 1", n)
+	}
+}
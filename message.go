@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
-	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"log/slog"
 	"strings"
 	"time"
 )
@@ -23,26 +23,26 @@ type Message struct {
 func initMsg(ctx context.Context, cfg *Config, topicType TopicType, message Message) (msg *rmq_client.Message, err error) {
 	//校验
 	if strings.Trim(message.Topic, "") == "" {
-		err = errors.New("topic必填")
-		debugLog(cfg, "消息初始化失败:%v", err)
+		err = newError(CodeInvalidMessage, ErrMsgRequired, "Topic")
+		logEvent(ctx, cfg, slog.LevelError, opMessageInit, "消息初始化失败", attrTopic(message.Topic), attrError(err))
 		return
 	}
 	if strings.Trim(message.Body, "") == "" {
-		err = errors.New("body必填")
-		debugLog(cfg, "消息初始化失败:%v", err)
+		err = newError(CodeInvalidMessage, ErrMsgRequired, "Body")
+		logEvent(ctx, cfg, slog.LevelError, opMessageInit, "消息初始化失败", attrTopic(message.Topic), attrError(err))
 		return
 	}
 	switch topicType {
 	case TopicFIFO:
 		if strings.Trim(message.MessageGroup, "") == "" {
-			err = errors.New("FIFO消息类型messageGroup必填")
-			debugLog(cfg, "消息初始化失败:%v", err)
+			err = newError(CodeInvalidMessage, ErrMsgFifoGroupRequired)
+			logEvent(ctx, cfg, slog.LevelError, opMessageInit, "消息初始化失败", attrTopic(message.Topic), attrError(err))
 			return
 		}
 	case TopicDelay:
 		if message.DeliveryTimestamp.IsZero() {
-			err = errors.New("Delay消息类型deliveryTimestamp必填")
-			debugLog(cfg, "消息初始化失败:%v", err)
+			err = newError(CodeInvalidMessage, ErrMsgDelayTimeRequired)
+			logEvent(ctx, cfg, slog.LevelError, opMessageInit, "消息初始化失败", attrTopic(message.Topic), attrError(err))
 			return
 		}
 	}
@@ -79,43 +79,50 @@ func initMsg(ctx context.Context, cfg *Config, topicType TopicType, message Mess
 	return
 }
 
-// IsTooManyRequest 是否触发了流控
-func IsTooManyRequest(err error) bool {
-	//如果是重试失败，则判断是否设置了补偿机制，有则调用
-	if e, ok := err.(*rmq_client.ErrRpcStatus); ok && e.GetCode() == int32(v2.Code_TOO_MANY_REQUESTS) {
-		return true
+// receiptMessageId 获取发送回执中的消息ID，多个时用逗号分隔
+func receiptMessageId(resp []*rmq_client.SendReceipt) string {
+	ids := make([]string, 0, len(resp))
+	for _, r := range resp {
+		if r != nil {
+			ids = append(ids, r.MessageID)
+		}
 	}
-	return false
+	return strings.Join(ids, ",")
 }
 
-// IsNoNewMessage 是否没有新消息
-func IsNoNewMessage(err error) bool {
-	//如果是重试失败，则判断是否设置了补偿机制，有则调用
-	if e, ok := err.(*rmq_client.ErrRpcStatus); ok && e.GetCode() == int32(v2.Code_MESSAGE_NOT_FOUND) {
-		return true
+// wrapSendError 按ClassifyError分类发送失败的错误，流控错误包装成ErrThrottled，其他错误原样返回
+// 官方客户端已在MaxAttempts内重试过，这里不再重试，可重试的错误由failoverProducer切换endpoint
+func wrapSendError(err error) error {
+	if err == nil || errors.Is(err, ErrThrottled) {
+		return err
 	}
-	return false
+	if ClassifyError(err) == ErrorClassThrottled {
+		return newError(CodeThrottled, ErrMsgThrottled).wrap(err)
+	}
+	return err
 }
 
 // Send 同步发送消息
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func Send(ctx context.Context, cfg *Config, producer rmq_client.Producer, topicType TopicType, msg Message) (resp []*rmq_client.SendReceipt, err error) {
 	if topicType == TopicTransaction {
-		err = errors.New("此方法不支持发送Transaction消息")
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = newError(CodeUnsupportedTopicType, ErrMsgMethodNotSupported, TopicTransaction)
+		logEvent(ctx, cfg, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 	message, err := initMsg(ctx, cfg, topicType, msg)
 	if err != nil {
-		debugLog(cfg, "消息发送失败:%v", err)
+		logEvent(ctx, cfg, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 
 	resp, err = producer.Send(ctx, message)
 	if err != nil {
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = wrapSendError(err)
+		logEvent(ctx, cfg, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
+	logEvent(ctx, cfg, slog.LevelDebug, opSend, "消息发送成功", attrTopic(msg.Topic), attrMessageId(receiptMessageId(resp)))
 	return
 }
 
@@ -125,14 +132,14 @@ type SendAsyncDealFunc func(ctx context.Context, msg Message, resp []*rmq_client
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func SendAsync(ctx context.Context, cfg *Config, producer rmq_client.Producer, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc) (err error) {
 	if dealFunc == nil {
-		err = errors.New("dealFunc必填")
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = newError(CodeInvalidArgument, ErrMsgRequired, "dealFunc")
+		logEvent(ctx, cfg, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 
 	if topicType == TopicTransaction {
-		err = errors.New("此方法不支持发送Transaction消息")
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = newError(CodeUnsupportedTopicType, ErrMsgMethodNotSupported, TopicTransaction)
+		logEvent(ctx, cfg, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 
@@ -142,6 +149,12 @@ func SendAsync(ctx context.Context, cfg *Config, producer rmq_client.Producer, t
 	}
 
 	producer.SendAsync(ctx, message, func(ctx context.Context, receipts []*rmq_client.SendReceipt, err error) {
+		err = wrapSendError(err)
+		if err != nil {
+			logEvent(ctx, cfg, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		} else {
+			logEvent(ctx, cfg, slog.LevelDebug, opSendAsync, "消息发送成功", attrTopic(msg.Topic), attrMessageId(receiptMessageId(receipts)))
+		}
 		dealFunc(ctx, msg, receipts, err)
 	})
 	return
@@ -155,8 +168,8 @@ type ConfirmFunc func(msg Message, resp []*rmq_client.SendReceipt) bool
 // 注意：事务消息的生产者不能和其他类型消息的生产者共用
 func SendTransaction(ctx context.Context, cfg *Config, producer rmq_client.Producer, message Message, confirmFunc ConfirmFunc) (resp []*rmq_client.SendReceipt, err error) {
 	if confirmFunc == nil {
-		err = errors.New("confirmFunc必填")
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = newError(CodeInvalidArgument, ErrMsgRequired, "confirmFunc")
+		logEvent(ctx, cfg, slog.LevelError, opSendTx, "消息发送失败", attrTopic(message.Topic), attrError(err))
 		return
 	}
 
@@ -168,9 +181,11 @@ func SendTransaction(ctx context.Context, cfg *Config, producer rmq_client.Produ
 	transaction := producer.BeginTransaction()
 	resp, err = producer.SendWithTransaction(ctx, msg, transaction)
 	if err != nil {
-		debugLog(cfg, "消息发送失败:%v", err)
+		err = wrapSendError(err)
+		logEvent(ctx, cfg, slog.LevelError, opSendTx, "消息发送失败", attrTopic(message.Topic), attrError(err))
 		return
 	}
+	logEvent(ctx, cfg, slog.LevelDebug, opSendTx, "事务消息发送成功", attrTopic(message.Topic), attrMessageId(receiptMessageId(resp)))
 	if confirmFunc(message, resp) {
 		return resp, transaction.Commit()
 	}
@@ -0,0 +1,91 @@
+package rocketmq_client
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+)
+
+// 指标通过opentelemetry的全局MeterProvider上报，需要由使用方设置，如gf的gmetric或otel的sdk，未设置时不上报
+
+// instrumentationName 指标的instrumentation名
+const instrumentationName = "github.com/yiqiang3344/rocketmq-client-go"
+
+// 指标名
+const (
+	MetricProducerFailover        = "rocketmq_client.producer.failover"         //生产者切换endpoint的次数，属性为from、to、reason
+	MetricProducerEndpointHealthy = "rocketmq_client.producer.endpoint.healthy" //生产者各endpoint是否健康，1为健康，属性为endpoint、active
+	MetricConsumerFifoGroups      = "rocketmq_client.consumer.fifo.groups"      //顺序消费中缓冲区里有消息、消费中或暂停推进的消息组数，属性为consumer_group、topic
+	MetricConsumerFifoDispatched  = "rocketmq_client.consumer.fifo.dispatched"  //顺序消费按消息组顺序分配给消费协程的消息数，属性为consumer_group、topic
+	MetricConsumerFifoWait        = "rocketmq_client.consumer.fifo.wait"        //顺序消费的消息在缓冲区中等待同组前面的消息消费完的时间，单位秒，属性为consumer_group、topic
+	MetricConsumerFifoBlocked     = "rocketmq_client.consumer.fifo.blocked"     //顺序消费因同组前面的消息消费失败而跳过、等待重新投递的消息数，属性为consumer_group、topic
+	MetricConsumerDeadLetter      = "rocketmq_client.consumer.dead_letter"      //转发到客户端死信主题的消息数，属性为consumer_group、topic（原主题）
+	MetricConsumerDedup           = "rocketmq_client.consumer.dedup"            //去重存储中已消费成功、直接确认的重复消息数，属性为consumer_group、topic
+)
+
+// 指标的属性名
+const (
+	MetricKeyEndpoint      = "endpoint"       //endpoint
+	MetricKeyFrom          = "from"           //切换前的endpoint
+	MetricKeyTo            = "to"             //切换后的endpoint
+	MetricKeyReason        = "reason"         //切换原因
+	MetricKeyActive        = "active"         //是否是当前使用的endpoint
+	MetricKeyConsumerGroup = "consumer_group" //消费者分组
+	MetricKeyTopic         = "topic"          //主题
+)
+
+var (
+	metricsOnce sync.Once
+	meter       metric.Meter
+
+	producerFailoverCounter       metric.Int64Counter
+	producerEndpointHealthyGauge  metric.Int64ObservableGauge
+	consumerFifoGroupsCounter     metric.Int64UpDownCounter
+	consumerFifoDispatchedCounter metric.Int64Counter
+	consumerFifoWaitHistogram     metric.Float64Histogram
+	consumerFifoBlockedCounter    metric.Int64Counter
+	consumerDeadLetterCounter     metric.Int64Counter
+	consumerDedupCounter          metric.Int64Counter
+)
+
+// initMetrics 初始化指标，只执行一次
+// 全局MeterProvider设置前创建的指标会在设置后自动生效，所以可以在任意时候初始化
+func initMetrics() {
+	metricsOnce.Do(func() {
+		meter = otel.Meter(instrumentationName)
+		//指标名和配置都是固定的，不会创建失败
+		producerFailoverCounter, _ = meter.Int64Counter(
+			MetricProducerFailover,
+			metric.WithDescription("Number of times a failover producer switched its active endpoint"),
+		)
+		producerEndpointHealthyGauge, _ = meter.Int64ObservableGauge(
+			MetricProducerEndpointHealthy,
+			metric.WithDescription("Whether an endpoint of a failover producer is healthy, 1 for healthy"),
+		)
+		consumerFifoGroupsCounter, _ = meter.Int64UpDownCounter(
+			MetricConsumerFifoGroups,
+			metric.WithDescription("Number of message groups buffered, consuming or blocked in a FIFO consumer"),
+		)
+		consumerFifoDispatchedCounter, _ = meter.Int64Counter(
+			MetricConsumerFifoDispatched,
+			metric.WithDescription("Number of messages dispatched to workers in message group order by a FIFO consumer"),
+		)
+		consumerFifoWaitHistogram, _ = meter.Float64Histogram(
+			MetricConsumerFifoWait,
+			metric.WithUnit("s"),
+			metric.WithDescription("Time a message waited in a FIFO consumer for earlier messages of its group"),
+		)
+		consumerFifoBlockedCounter, _ = meter.Int64Counter(
+			MetricConsumerFifoBlocked,
+			metric.WithDescription("Number of messages skipped by a FIFO consumer because an earlier message of their group failed"),
+		)
+		consumerDeadLetterCounter, _ = meter.Int64Counter(
+			MetricConsumerDeadLetter,
+			metric.WithDescription("Number of messages forwarded to the client-side dead-letter topic"),
+		)
+		consumerDedupCounter, _ = meter.Int64Counter(
+			MetricConsumerDedup,
+			metric.WithDescription("Number of duplicate messages acked without consuming because the dedup store has them"),
+		)
+	})
+}
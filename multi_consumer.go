@@ -0,0 +1,93 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// MessageSource 消息来源的集群信息
+type MessageSource struct {
+	Name          string //集群名，即MultiClusterConsume中cfgs的key
+	Endpoint      string //地址
+	NameSpace     string //命名空间
+	ConsumerGroup string //消费者分组
+}
+
+type messageSourceKey struct{}
+
+// MessageSourceFromContext 获取消费方法ctx中的消息来源，不是MultiClusterConsume消费的消息时ok为false
+func MessageSourceFromContext(ctx context.Context) (source MessageSource, ok bool) {
+	source, ok = ctx.Value(messageSourceKey{}).(MessageSource)
+	return
+}
+
+// MultiClusterConsume 同时消费多个集群或命名空间的同一逻辑主题，每个集群启动一个简单消费者，消息都交给consumeFunc处理
+// cfgs的key为集群名，消费者选项对所有集群生效；consumeFunc中的consumer会把Ack等操作发回消息来源的集群，
+// 来源信息可通过MessageSourceFromContext获取。
+// 注意：不同集群的消息会并发调用consumeFunc；任一集群启动失败时会注销已启动的消费者并返回错误；stopFunc会同时注销所有集群的消费者，返回汇总的错误
+func MultiClusterConsume(ctx context.Context, cfgs map[string]*Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	return multiClusterConsume(ctx, cfgs, SimpleConsume, consumeFunc, oFunc...)
+}
+
+func multiClusterConsume(ctx context.Context, cfgs map[string]*Config, consume func(ctx context.Context, cfg *Config, consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (func() error, error), consumeFunc ConsumeFunc, oFunc ...ConsumerOptionFunc) (stopFunc func() error, err error) {
+	if len(cfgs) == 0 {
+		return nil, newError(CodeInvalidConfig, ErrMsgRequired, "cfgs")
+	}
+	names := make([]string, 0, len(cfgs))
+	for name, cfg := range cfgs {
+		if cfg == nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgRequired, "cfgs["+name+"]")
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stopFuncs := make([]func() error, 0, len(names))
+	stopAll := func() error {
+		var wg sync.WaitGroup
+		errs := make([]error, len(stopFuncs))
+		for i, f := range stopFuncs {
+			wg.Add(1)
+			go func(i int, f func() error) {
+				defer wg.Done()
+				errs[i] = f()
+			}(i, f)
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+	for _, name := range names {
+		cfg := cfgs[name]
+		source := MessageSource{
+			Name:          name,
+			Endpoint:      cfg.Endpoint,
+			NameSpace:     cfg.NameSpace,
+			ConsumerGroup: cfg.ConsumerGroup,
+		}
+		f, err := consume(ctx, cfg, func(ctx context.Context, msg *rmq_client.MessageView, consumer Consumer) error {
+			return consumeFunc(context.WithValue(ctx, messageSourceKey{}, source), msg, consumer)
+		}, oFunc...)
+		if err != nil {
+			logEvent(ctx, cfg, slog.LevelError, opConsumerStart, "多集群消费者启动失败，注销已启动的消费者", slog.String("source", name), attrError(err))
+			_ = stopAll()
+			return nil, err
+		}
+		stopFuncs = append(stopFuncs, f)
+	}
+
+	var (
+		once    sync.Once
+		stopErr error
+	)
+	stopFunc = func() error {
+		once.Do(func() {
+			stopErr = stopAll()
+		})
+		return stopErr
+	}
+	return
+}
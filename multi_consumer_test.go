@@ -0,0 +1,124 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fakeClusters 模拟每个集群的SimpleConsume，记录启动和注销的集群
+type fakeClusters struct {
+	mu       sync.Mutex
+	started  []string
+	stopped  []string
+	startErr map[string]error
+	stopErr  map[string]error
+	funcs    map[string]ConsumeFunc
+}
+
+func (f *fakeClusters) consume(_ context.Context, cfg *Config, consumeFunc ConsumeFunc, _ ...ConsumerOptionFunc) (func() error, error) {
+	name := cfg.Endpoint
+	if err := f.startErr[name]; err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, name)
+	f.funcs[name] = consumeFunc
+	return func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.stopped = append(f.stopped, name)
+		return f.stopErr[name]
+	}, nil
+}
+
+func newFakeClusters() *fakeClusters {
+	return &fakeClusters{funcs: map[string]ConsumeFunc{}}
+}
+
+func testClusterCfgs(names ...string) map[string]*Config {
+	cfgs := map[string]*Config{}
+	for _, name := range names {
+		//用Endpoint区分集群
+		cfgs[name] = &Config{Endpoint: name, NameSpace: "ns_" + name, ConsumerGroup: "cg"}
+	}
+	return cfgs
+}
+
+func TestMultiClusterConsumeSource(t *testing.T) {
+	f := newFakeClusters()
+	var sources []MessageSource
+	consumeFunc := func(ctx context.Context, _ *rmq_client.MessageView, _ Consumer) error {
+		source, ok := MessageSourceFromContext(ctx)
+		if !ok {
+			t.Error("no message source in ctx")
+		}
+		sources = append(sources, source)
+		return nil
+	}
+	stop, err := multiClusterConsume(context.Background(), testClusterCfgs("a", "b"), f.consume, consumeFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	for _, name := range []string{"a", "b"} {
+		_ = f.funcs[name](context.Background(), nil, nil)
+	}
+	want := []MessageSource{
+		{Name: "a", Endpoint: "a", NameSpace: "ns_a", ConsumerGroup: "cg"},
+		{Name: "b", Endpoint: "b", NameSpace: "ns_b", ConsumerGroup: "cg"},
+	}
+	if !slices.Equal(sources, want) {
+		t.Fatalf("sources = %+v, want %+v", sources, want)
+	}
+	if _, ok := MessageSourceFromContext(context.Background()); ok {
+		t.Fatal("message source in a plain ctx")
+	}
+}
+
+func TestMultiClusterConsumeStartFailure(t *testing.T) {
+	f := newFakeClusters()
+	startErr := errors.New("start c")
+	f.startErr = map[string]error{"c": startErr}
+	stop, err := multiClusterConsume(context.Background(), testClusterCfgs("a", "b", "c"), f.consume, func(context.Context, *rmq_client.MessageView, Consumer) error { return nil })
+	if err != startErr || stop != nil {
+		t.Fatalf("err = %v, want the start error", err)
+	}
+	slices.Sort(f.stopped)
+	if !slices.Equal(f.started, []string{"a", "b"}) || !slices.Equal(f.stopped, []string{"a", "b"}) {
+		t.Fatalf("started %v, stopped %v", f.started, f.stopped)
+	}
+
+	if _, err = multiClusterConsume(context.Background(), nil, f.consume, nil); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("no cfgs = %v", err)
+	}
+	if _, err = multiClusterConsume(context.Background(), map[string]*Config{"a": nil}, f.consume, nil); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("nil cfg = %v", err)
+	}
+}
+
+func TestMultiClusterConsumeStop(t *testing.T) {
+	f := newFakeClusters()
+	errA, errC := errors.New("stop a"), errors.New("stop c")
+	f.stopErr = map[string]error{"a": errA, "c": errC}
+	stop, err := multiClusterConsume(context.Background(), testClusterCfgs("a", "b", "c"), f.consume, func(context.Context, *rmq_client.MessageView, Consumer) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stop()
+	if !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Fatalf("stop error = %v, want both errors joined", err)
+	}
+	slices.Sort(f.stopped)
+	if !slices.Equal(f.stopped, []string{"a", "b", "c"}) {
+		t.Fatalf("stopped = %v", f.stopped)
+	}
+	//再次调用不重复注销
+	if err2 := stop(); err2 != err || len(f.stopped) != 3 {
+		t.Fatalf("second stop = %v, stopped %v", err2, f.stopped)
+	}
+}
@@ -0,0 +1,278 @@
+package rocketmq_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	innerMD "github.com/apache/rocketmq-clients/golang/v5/metadata"
+	"github.com/apache/rocketmq-clients/golang/v5/pkg/utils"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PreflightMode 启动前预检的模式
+type PreflightMode string
+
+const (
+	PreflightDisabled PreflightMode = ""          //不预检，默认
+	PreflightWarn     PreflightMode = "WARN"      //预检不通过时只记录警告日志，继续启动
+	PreflightFailFast PreflightMode = "FAIL_FAST" //预检不通过时启动失败，返回的错误中包含预检报告
+)
+
+// 预检项的类型
+const (
+	PreflightKindConnectivity  = "connectivity"   //endpoint连通性
+	PreflightKindTopicRoute    = "topic_route"    //主题路由，同时校验凭证和命名空间
+	PreflightKindConsumerGroup = "consumer_group" //消费者分组
+	PreflightKindSubscription  = "subscription"   //订阅表达式语法
+)
+
+// preflightTimeout 单个预检请求的超时时间
+var preflightTimeout = 5 * time.Second
+
+// PreflightCheck 单个预检项的结果
+type PreflightCheck struct {
+	Kind   string //预检项类型
+	Target string //检查对象，如endpoint、主题、消费者分组
+	Err    error  //为nil表示通过
+}
+
+// PreflightReport 预检报告，不通过时可作为错误使用，并可用errors.As从启动返回的错误中取出
+type PreflightReport struct {
+	Endpoint  string
+	NameSpace string
+	Checks    []PreflightCheck
+}
+
+// OK 所有预检项是否都通过
+func (r *PreflightReport) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed 未通过的预检项
+func (r *PreflightReport) Failed() []PreflightCheck {
+	var ret []PreflightCheck
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Err 所有预检项都通过时返回nil，否则返回错误码为PREFLIGHT_FAILED的错误，原始错误为报告本身
+func (r *PreflightReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return newError(CodePreflightFailed, ErrMsgPreflightFailed, len(failed), len(r.Checks)).wrap(r)
+}
+
+// Error 每个未通过的预检项一行
+func (r *PreflightReport) Error() string {
+	var b strings.Builder
+	for i, c := range r.Failed() {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s: %v", c.Kind, c.Target, c.Err)
+	}
+	return b.String()
+}
+
+// Unwrap 未通过的预检项的错误，可用errors.Is、errors.As和IsTopicNotFound等方法判断
+func (r *PreflightReport) Unwrap() []error {
+	var errs []error
+	for _, c := range r.Failed() {
+		errs = append(errs, c.Err)
+	}
+	return errs
+}
+
+func (r *PreflightReport) add(kind, target string, err error) {
+	r.Checks = append(r.Checks, PreflightCheck{Kind: kind, Target: target, Err: err})
+}
+
+// Preflight 检查endpoint连通性、主题路由、消费者分组（cfg.ConsumerGroup不为空时）以及订阅表达式语法，返回汇总的报告
+// topics为需要检查路由的主题，subExpressions中的主题也会检查路由；endpoint连不上时不再做远程检查
+func Preflight(ctx context.Context, cfg *Config, topics []string, subExpressions map[string]*FilterExpression) (report *PreflightReport, err error) {
+	if err = checkCfg(cfg); err != nil {
+		return
+	}
+	report = &PreflightReport{
+		Endpoint:  cfg.Endpoint,
+		NameSpace: cfg.NameSpace,
+	}
+
+	//订阅表达式在本地检查
+	subTopics := make([]string, 0, len(subExpressions))
+	for topic := range subExpressions {
+		subTopics = append(subTopics, topic)
+	}
+	sort.Strings(subTopics)
+	for _, topic := range subTopics {
+		report.add(PreflightKindSubscription, topic, checkFilterExpression(topic, subExpressions[topic]))
+	}
+
+	if err = probeEndpoint(ctx, cfg.Endpoint); err != nil {
+		report.add(PreflightKindConnectivity, cfg.Endpoint, err)
+		return report, nil
+	}
+
+	endpoints, err := utils.ParseTarget(cfg.Endpoint)
+	if err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgInvalidEndpoint, cfg.Endpoint).wrap(err)
+	}
+	rc, release, err := getRmqCfg(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	//和官方客户端一样通过NewRpcClient创建连接，以便使用实例的TLS和凭证设置
+	installSdkConnHook()
+	cli, err := rmq_client.NewRpcClient(utils.ParseAddress(utils.SelectAnAddress(endpoints)))
+	if err != nil {
+		report.add(PreflightKindConnectivity, cfg.Endpoint, err)
+		return report, nil
+	}
+	defer func() {
+		_ = cli.GracefulStop()
+	}()
+	report.add(PreflightKindConnectivity, cfg.Endpoint, nil)
+
+	pc := &preflightClient{
+		cli:       cli,
+		rc:        rc,
+		endpoints: endpoints,
+		clientId:  utils.GenClientID(),
+	}
+	allTopics := append(append([]string{}, topics...), subTopics...)
+	sort.Strings(allTopics)
+	for i, topic := range allTopics {
+		if i > 0 && topic == allTopics[i-1] {
+			continue
+		}
+		report.add(PreflightKindTopicRoute, topic, pc.queryRoute(ctx, topic))
+	}
+	if cfg.ConsumerGroup != "" {
+		report.add(PreflightKindConsumerGroup, cfg.ConsumerGroup, pc.heartbeat(ctx, cfg.ConsumerGroup))
+	}
+	return report, nil
+}
+
+// runPreflight 按模式执行预检，FailFast时返回预检不通过的错误
+func runPreflight(ctx context.Context, cfg *Config, mode PreflightMode, op string, topics []string, subExpressions map[string]*FilterExpression) error {
+	if mode == PreflightDisabled {
+		return nil
+	}
+	if !isValidPreflightMode(mode) {
+		err := newError(CodeInvalidConfig, ErrMsgInvalidPreflightMode, "Preflight", mode)
+		logEvent(ctx, cfg, slog.LevelError, op, "预检参数不合法", attrError(err))
+		return err
+	}
+	report, err := Preflight(ctx, cfg, topics, subExpressions)
+	if err != nil {
+		return err
+	}
+	if report.OK() {
+		logEvent(ctx, cfg, slog.LevelInfo, op, "预检通过", slog.Int("checks", len(report.Checks)))
+		return nil
+	}
+	level := slog.LevelWarn
+	if mode == PreflightFailFast {
+		level = slog.LevelError
+	}
+	for _, c := range report.Failed() {
+		logEvent(ctx, cfg, level, op, "预检不通过", slog.String("kind", c.Kind), slog.String("target", c.Target), attrError(c.Err))
+	}
+	if mode == PreflightFailFast {
+		return report.Err()
+	}
+	return nil
+}
+
+func isValidPreflightMode(mode PreflightMode) bool {
+	switch mode {
+	case PreflightDisabled, PreflightWarn, PreflightFailFast:
+		return true
+	}
+	return false
+}
+
+// preflightClient 直接调用服务端接口做预检，签名方式和官方客户端相同
+type preflightClient struct {
+	cli       rmq_client.RpcClient
+	rc        *rmq_client.Config
+	endpoints *v2.Endpoints
+	clientId  string
+}
+
+func (c *preflightClient) sign(ctx context.Context) context.Context {
+	now := time.Now().Format("20060102T150405Z")
+	return metadata.AppendToOutgoingContext(ctx,
+		innerMD.LanguageKey, innerMD.LanguageValue,
+		innerMD.ProtocolKey, innerMD.ProtocolValue,
+		innerMD.RequestID, uuid.New().String(),
+		innerMD.VersionKey, innerMD.VersionValue,
+		innerMD.ClintID, c.clientId,
+		innerMD.NameSpace, c.rc.NameSpace,
+		innerMD.DateTime, now,
+		innerMD.Authorization, signAuthorization(c.rc.Credentials.AccessKey, c.rc.Credentials.AccessSecret, now),
+	)
+}
+
+// statusError 把服务端的返回码转为官方客户端的错误，以便用ClassifyError分类
+func statusError(status *v2.Status) error {
+	if status.GetCode() == v2.Code_OK {
+		return nil
+	}
+	return &rmq_client.ErrRpcStatus{
+		Code:    int32(status.GetCode()),
+		Message: status.GetMessage(),
+	}
+}
+
+func (c *preflightClient) queryRoute(ctx context.Context, topic string) error {
+	ctx, cancel := context.WithTimeout(c.sign(ctx), preflightTimeout)
+	defer cancel()
+	resp, err := c.cli.QueryRoute(ctx, &v2.QueryRouteRequest{
+		Topic: &v2.Resource{
+			Name:              topic,
+			ResourceNamespace: c.rc.NameSpace,
+		},
+		Endpoints: c.endpoints,
+	})
+	if err != nil {
+		return err
+	}
+	if err = statusError(resp.GetStatus()); err != nil {
+		return err
+	}
+	if len(resp.GetMessageQueues()) == 0 {
+		return errors.New("no available message queue")
+	}
+	return nil
+}
+
+func (c *preflightClient) heartbeat(ctx context.Context, group string) error {
+	ctx, cancel := context.WithTimeout(c.sign(ctx), preflightTimeout)
+	defer cancel()
+	resp, err := c.cli.HeartBeat(ctx, &v2.HeartbeatRequest{
+		Group: &v2.Resource{
+			Name:              group,
+			ResourceNamespace: c.rc.NameSpace,
+		},
+		ClientType: v2.ClientType_SIMPLE_CONSUMER,
+	})
+	if err != nil {
+		return err
+	}
+	return statusError(resp.GetStatus())
+}
@@ -2,8 +2,10 @@ package rocketmq_client
 
 import (
 	"context"
-	"errors"
 	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"slices"
+	"time"
 )
 
 type Producer interface {
@@ -14,13 +16,16 @@ type Producer interface {
 }
 
 func GetProducer(cfg *Config, oFunc ...ProducerOptionFunc) (producer Producer, err error) {
-	p, err := startProducer(cfg, oFunc...)
+	p, options, release, err := startProducer(cfg, oFunc...)
 	if err != nil {
 		return
 	}
 	producer = &defaultProducer{
 		Cfg:      cfg,
 		producer: p,
+		options:  options,
+		release:  release,
+		stats:    newClientStats(healthKindProducer),
 	}
 	return
 }
@@ -28,19 +33,55 @@ func GetProducer(cfg *Config, oFunc ...ProducerOptionFunc) (producer Producer, e
 type defaultProducer struct {
 	Cfg      *Config
 	producer rmq_client.Producer
+	options  *ProducerOptions
+	release  func() //释放生产者附带的资源，如凭证刷新
+	stats    *clientStats
 }
 
-func (s *defaultProducer) debugLog(format string, args ...any) {
-	debugLog(s.Cfg, format, args...)
+// Health 生产者的健康状态
+func (s *defaultProducer) Health() ClientHealth {
+	return s.stats.health("")
+}
+
+// track 开始记录一次发送的健康统计，返回结束方法
+func (s *defaultProducer) track() func(err error) {
+	s.stats.begin()
+	return func(err error) {
+		s.stats.end()
+		s.stats.record(err)
+	}
+}
+
+// checkTopicType 配置了主题类型时，校验发送的类型和主题的类型是否一致
+func (s *defaultProducer) checkTopicType(topic string, topicType TopicType) error {
+	if s.options == nil {
+		return nil
+	}
+	if t, ok := s.options.TopicTypes[topic]; ok && t != topicType {
+		return newError(CodeTopicTypeMismatch, ErrMsgTopicTypeMismatch, topic, t, topicType)
+	}
+	return nil
+}
+
+func (s *defaultProducer) logEvent(ctx context.Context, level slog.Level, op string, msg string, attrs ...slog.Attr) {
+	logEvent(ctx, s.Cfg, level, op, msg, attrs...)
 }
 
 // StopProducer 注销生产者
 func (s *defaultProducer) Stop() error {
+	//已注销时直接返回，避免重复注销
+	if s.producer == nil {
+		return nil
+	}
 	err := stopProducer(s.Cfg, s.producer)
 	if err != nil {
 		return err
 	}
+	if s.release != nil {
+		s.release()
+	}
 	s.producer = nil
+	s.stats.started.Store(false)
 	return nil
 }
 
@@ -48,12 +89,18 @@ func (s *defaultProducer) Stop() error {
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func (s *defaultProducer) Send(ctx context.Context, topicType TopicType, msg Message) (resp []*rmq_client.SendReceipt, err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	if err = s.checkTopicType(msg.Topic, topicType); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
 
+	done := s.track()
 	resp, err = Send(ctx, s.Cfg, s.producer, topicType, msg)
+	done(err)
 	return
 }
 
@@ -61,11 +108,22 @@ func (s *defaultProducer) Send(ctx context.Context, topicType TopicType, msg Mes
 // 可支持普通、延迟、顺序类型的消息，不支持事务消息
 func (s *defaultProducer) SendAsync(ctx context.Context, topicType TopicType, msg Message, dealFunc SendAsyncDealFunc) (err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	if err = s.checkTopicType(msg.Topic, topicType); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
 		return
 	}
-	err = SendAsync(ctx, s.Cfg, s.producer, topicType, msg, dealFunc)
+	done := s.track()
+	err = SendAsync(ctx, s.Cfg, s.producer, topicType, msg, func(ctx context.Context, msg Message, resp []*rmq_client.SendReceipt, err error) {
+		done(err)
+		dealFunc(ctx, msg, resp, err)
+	})
+	if err != nil {
+		done(err)
+	}
 	return
 }
 
@@ -73,18 +131,31 @@ func (s *defaultProducer) SendAsync(ctx context.Context, topicType TopicType, ms
 // 注意：事务消息的生产者不能和其他类型消息的生产者共用
 func (s *defaultProducer) SendTransaction(ctx context.Context, message Message, confirmFunc ConfirmFunc) (err error) {
 	if s.producer == nil {
-		err = errors.New("请先初始化生产者")
-		s.debugLog("消息发送失败:%v", err)
+		err = newError(CodeProducerNotStarted, ErrMsgProducerNotStarted)
+		s.logEvent(ctx, slog.LevelError, opSendTx, "消息发送失败", attrTopic(message.Topic), attrError(err))
 		return
 	}
+	if err = s.checkTopicType(message.Topic, TopicTransaction); err != nil {
+		s.logEvent(ctx, slog.LevelError, opSendTx, "消息发送失败", attrTopic(message.Topic), attrError(err))
+		return
+	}
+	done := s.track()
 	_, err = SendTransaction(ctx, s.Cfg, s.producer, message, confirmFunc)
+	done(err)
 	return
 }
 
 type ProducerOptions struct {
 	Topics             []string                   //支持的主题列表，可选
+	TopicTypes         map[string]TopicType       //主题及其类型，可选，设置后会加入主题列表，且发送时会校验消息类型和主题类型是否一致
 	MaxAttempts        int32                      //重试次数，可选
 	transactionChecker SendTransactionCheckerFunc //事务检查器，事务消息必填
+
+	Preflight PreflightMode //启动前预检模式，可选，默认不预检，会检查连通性和所有主题的路由
+
+	//以下只对GetFailoverProducer生效
+	HealthCheckInterval time.Duration //endpoint健康检查间隔，可选，默认5秒
+	FailbackAfter       int           //优先级更高的endpoint连续健康检查通过多少次后切回，可选，默认3次
 }
 
 func WithProducerOptionTopics(Topics ...string) ProducerOptionFunc {
@@ -95,6 +166,12 @@ func WithProducerOptionTopics(Topics ...string) ProducerOptionFunc {
 
 type ProducerOptionFunc func(options *ProducerOptions)
 
+func WithProducerOptionTopicTypes(topicTypes map[string]TopicType) ProducerOptionFunc {
+	return func(o *ProducerOptions) {
+		o.TopicTypes = topicTypes
+	}
+}
+
 func WithProducerOptionMaxAttempts(maxAttempts int32) ProducerOptionFunc {
 	return func(o *ProducerOptions) {
 		o.MaxAttempts = maxAttempts
@@ -109,48 +186,95 @@ func WithProducerOptionTransactionChecker(transactionChecker SendTransactionChec
 	}
 }
 
-func startProducer(cfg *Config, oFunc ...ProducerOptionFunc) (producer rmq_client.Producer, err error) {
+func WithProducerOptionPreflight(mode PreflightMode) ProducerOptionFunc {
+	return func(o *ProducerOptions) {
+		o.Preflight = mode
+	}
+}
+
+// WithProducerOptionHealthCheckInterval 故障切换生产者的健康检查间隔，健康检查只检查endpoint的TCP连通性，见GetFailoverProducer
+func WithProducerOptionHealthCheckInterval(interval time.Duration) ProducerOptionFunc {
+	return func(o *ProducerOptions) {
+		o.HealthCheckInterval = interval
+	}
+}
+
+// WithProducerOptionFailbackAfter 优先级更高的endpoint连续多少次TCP健康检查通过后切回，不代表该集群能正常处理请求
+func WithProducerOptionFailbackAfter(failbackAfter int) ProducerOptionFunc {
+	return func(o *ProducerOptions) {
+		o.FailbackAfter = failbackAfter
+	}
+}
+
+func newProducerOptions(oFunc ...ProducerOptionFunc) *ProducerOptions {
+	options := &ProducerOptions{
+		MaxAttempts:         3,
+		HealthCheckInterval: 5 * time.Second,
+		FailbackAfter:       3,
+	}
+	for _, f := range oFunc {
+		f(options)
+	}
+	//主题类型中的主题加入主题列表
+	for topic := range options.TopicTypes {
+		if !slices.Contains(options.Topics, topic) {
+			options.Topics = append(options.Topics, topic)
+		}
+	}
+	return options
+}
+
+func startProducer(cfg *Config, oFunc ...ProducerOptionFunc) (producer rmq_client.Producer, options *ProducerOptions, release func(), err error) {
 	err = checkCfg(cfg)
 	if err != nil {
 		return
 	}
 
-	o := ProducerOptions{
-		MaxAttempts: 3,
-	}
-	options := &o
-	if len(oFunc) > 0 {
-		for _, f := range oFunc {
-			f(options)
-		}
+	options = newProducerOptions(oFunc...)
+	if err = runPreflight(context.Background(), cfg, options.Preflight, opProducerStart, options.Topics, nil); err != nil {
+		return
 	}
 
-	producer, err = rmq_client.NewProducer(
-		getRmqCfg(cfg),
-		rmq_client.WithTopics(options.Topics...),
-		rmq_client.WithMaxAttempts(options.MaxAttempts),
-		rmq_client.WithTransactionChecker(&rmq_client.TransactionChecker{
-			Check: options.transactionChecker,
-		}),
-	)
+	rc, release, err := getRmqCfg(context.Background(), cfg)
+	if err != nil {
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStart, "生产者初始化失败", attrError(err))
+		return
+	}
+	defer func() {
+		if err != nil {
+			release()
+			release = nil
+		}
+	}()
+	producer, err = newSdkClient(cfg, func() (rmq_client.Producer, error) {
+		return rmq_client.NewProducer(
+			rc,
+			rmq_client.WithTopics(options.Topics...),
+			rmq_client.WithMaxAttempts(options.MaxAttempts),
+			rmq_client.WithTransactionChecker(&rmq_client.TransactionChecker{
+				Check: options.transactionChecker,
+			}),
+		)
+	})
 	if err != nil {
-		debugLog(cfg, "生产者初始化失败:%v", err)
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStart, "生产者初始化失败", attrError(err))
 		return
 	}
 	err = producer.Start()
 	if err != nil {
-		debugLog(cfg, "生产者启动失败:%v", err)
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStart, "生产者启动失败", attrError(err))
 		return
 	}
+	logEvent(context.Background(), cfg, slog.LevelInfo, opProducerStart, "生产者启动成功")
 	return
 }
 
 func stopProducer(cfg *Config, producer rmq_client.Producer) error {
 	err := producer.GracefulStop()
 	if err != nil {
-		debugLog(cfg, "生产者注销失败:%v", err)
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStop, "生产者注销失败", attrError(err))
 		return err
 	}
-	debugLog(cfg, "生产者注销成功")
+	logEvent(context.Background(), cfg, slog.LevelInfo, opProducerStop, "生产者注销成功")
 	return nil
 }
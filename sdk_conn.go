@@ -0,0 +1,99 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	innerMD "github.com/apache/rocketmq-clients/golang/v5/metadata"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+	"sync"
+)
+
+// 官方客户端创建连接时不接受任何实例级别的参数，这里在第一次创建客户端时包装NewRpcClient，
+// 按连接地址加上登记的TLS设置，并给每个连接加上拦截器：按请求签名中的占位accessKey找到对应实例的凭证，
+// 路由查询结果中的地址沿用该连接的TLS设置。
+
+const (
+	sessionTokenHeader  = "x-mq-session-token"
+	authorizationHeader = "authorization"
+	credentialPrefix    = "Credential="
+)
+
+var sdkConnOnce sync.Once
+
+// installSdkConnHook 包装官方客户端创建连接的方法，只执行一次
+func installSdkConnHook() {
+	sdkConnOnce.Do(func() {
+		newRpcClient := rmq_client.NewRpcClient
+		rmq_client.NewRpcClient = func(target string, opts ...rmq_client.RpcClientOption) (rmq_client.RpcClient, error) {
+			connOpts := append(rpcClientConnOptions(target), rmq_client.WithDialOptions(
+				grpc.WithChainUnaryInterceptor(sessionCredentialsUnaryInterceptor, routeTLSUnaryInterceptor(target)),
+				grpc.WithChainStreamInterceptor(sessionCredentialsStreamInterceptor),
+			))
+			opts = append(opts, rmq_client.WithRpcClientConnOption(connOpts...))
+			return newRpcClient(target, opts...)
+		}
+	})
+}
+
+// withSessionCredentials 请求签名中的accessKey是凭证会话的占位accessKey时，用会话的当前凭证重新签名，有security token时附加到请求头
+func withSessionCredentials(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	for _, v := range md.Get(authorizationHeader) {
+		i := strings.Index(v, credentialPrefix)
+		if i < 0 {
+			continue
+		}
+		accessKey, _, _ := strings.Cut(v[i+len(credentialPrefix):], "/")
+		session, ok := lookupCredentialsSession(accessKey)
+		if !ok {
+			return ctx
+		}
+		c := session.current.Load()
+		var dateTime string
+		if values := md.Get(innerMD.DateTime); len(values) > 0 {
+			dateTime = values[0]
+		}
+		md = md.Copy()
+		md.Set(authorizationHeader, signAuthorization(c.AccessKey, c.AccessSecret, dateTime))
+		if c.SecurityToken != "" {
+			md.Set(sessionTokenHeader, c.SecurityToken)
+		}
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	return ctx
+}
+
+func sessionCredentialsUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(withSessionCredentials(ctx), method, req, reply, cc, opts...)
+}
+
+func sessionCredentialsStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withSessionCredentials(ctx), desc, cc, method, opts...)
+}
+
+// routeTLSUnaryInterceptor 路由查询结果中的地址沿用target的TLS配置
+func routeTLSUnaryInterceptor(target string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			return err
+		}
+		switch r := reply.(type) {
+		case *v2.QueryRouteResponse:
+			registerRouteTLS(target, r.GetMessageQueues())
+		case *v2.QueryAssignmentResponse:
+			queues := make([]*v2.MessageQueue, 0, len(r.GetAssignments()))
+			for _, a := range r.GetAssignments() {
+				queues = append(queues, a.GetMessageQueue())
+			}
+			registerRouteTLS(target, queues)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,197 @@
+package rocketmq_client
+
+import (
+	"context"
+	"github.com/natefinch/lumberjack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	_ "unsafe"
+)
+
+// 官方客户端只有一个包级别的日志记录器，只能通过环境变量和ResetLogger调整，会影响整个进程。
+// 这里在第一次创建客户端时把它替换成按实例路由的记录器：官方客户端创建时会调用With("client_id", ...)
+// 派生出自己的记录器，此时把它绑定到正在创建的实例上，之后该客户端的日志都写到这个实例的LogPath或终端，
+// 实例配置了Logger时同时转发到Logger。不属于任何客户端的日志（如连接管理）仍然写到官方默认的记录器。
+// sugarBaseLogger是官方客户端未导出的变量，升级官方客户端时需确认sdk_log_test.go通过。
+
+//go:linkname sdkSugarLogger github.com/apache/rocketmq-clients/golang/v5.sugarBaseLogger
+var sdkSugarLogger *zap.SugaredLogger
+
+const (
+	sdkLogFileName    = "rocketmq_client_go.log"
+	sdkLogMaxFileSize = 1024 //单个日志文件大小，单位MB
+	sdkLogMaxBackups  = 10
+	sdkLogClientIdKey = "client_id"
+	opSdk             = "sdk"
+)
+
+var (
+	sdkLogOnce    sync.Once
+	sdkLogMu      sync.Mutex //串行化官方客户端的构造（不含Start，没有网络请求），保证日志绑定到正确的实例
+	sdkLogPending atomic.Pointer[sdkLogSink]
+
+	sdkLogWritersMu sync.Mutex
+	sdkLogWriters   = map[string]zapcore.WriteSyncer{} //同一路径的日志文件共用一个writer
+)
+
+// installSdkLogger 替换官方客户端的全局日志记录器，只执行一次
+func installSdkLogger() {
+	sdkLogOnce.Do(func() {
+		sdkSugarLogger = zap.New(&sdkRouterCore{fallback: sdkSugarLogger.Desugar().Core()}, zap.AddCaller()).Sugar()
+	})
+}
+
+// newSdkClient 创建官方客户端，创建期间派生的日志记录器会绑定到cfg对应的实例
+func newSdkClient[T any](cfg *Config, newFunc func() (T, error)) (T, error) {
+	installSdkLogger()
+	installSdkConnHook()
+	sdkLogMu.Lock()
+	defer sdkLogMu.Unlock()
+	sdkLogPending.Store(newSdkLogSink(cfg))
+	defer sdkLogPending.Store(nil)
+	return newFunc()
+}
+
+// sdkLogSink 单个实例的官方日志输出，写到实例自己的文件或终端，配置了Logger时同时转发到Logger
+type sdkLogSink struct {
+	cfg  *Config
+	core zapcore.Core
+}
+
+func newSdkLogSink(cfg *Config) *sdkLogSink {
+	var ws zapcore.WriteSyncer
+	if cfg.LogStdout {
+		ws = zapcore.Lock(os.Stdout)
+	} else {
+		ws = sdkLogWriter(cfg.LogPath)
+	}
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	return &sdkLogSink{
+		cfg:  cfg,
+		core: zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), ws, zapcore.InfoLevel),
+	}
+}
+
+func sdkLogWriter(logPath string) zapcore.WriteSyncer {
+	sdkLogWritersMu.Lock()
+	defer sdkLogWritersMu.Unlock()
+	if ws, ok := sdkLogWriters[logPath]; ok {
+		return ws
+	}
+	ws := zapcore.AddSync(&lumberjack.Logger{
+		Filename:   filepath.Join(logPath, sdkLogFileName),
+		MaxSize:    sdkLogMaxFileSize,
+		MaxBackups: sdkLogMaxBackups,
+	})
+	sdkLogWriters[logPath] = ws
+	return ws
+}
+
+func (s *sdkLogSink) enabled(level zapcore.Level) bool {
+	return level >= zapcore.InfoLevel
+}
+
+func (s *sdkLogSink) write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := s.core.Write(ent, fields)
+	//只转发到显式配置的Logger，不输出到Debug和DebugHandlerFunc
+	if s.cfg.Logger == nil {
+		return err
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, enc.Fields[k]))
+	}
+	logEvent(context.Background(), s.cfg, sdkSlogLevel(ent.Level), opSdk, ent.Message, attrs...)
+	return err
+}
+
+func sdkSlogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level < zapcore.InfoLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// sdkRouterCore 按实例路由的zap core，未绑定实例时写到官方默认的记录器
+type sdkRouterCore struct {
+	fallback zapcore.Core
+	sink     *sdkLogSink
+	fields   []zapcore.Field
+}
+
+func (c *sdkRouterCore) Enabled(level zapcore.Level) bool {
+	if c.sink == nil {
+		return c.fallback.Enabled(level)
+	}
+	return c.sink.enabled(level)
+}
+
+func (c *sdkRouterCore) With(fields []zapcore.Field) zapcore.Core {
+	n := *c
+	if n.sink == nil {
+		for _, f := range fields {
+			if f.Key == sdkLogClientIdKey {
+				n.sink = sdkLogPending.Load()
+				break
+			}
+		}
+	}
+	if n.sink == nil {
+		n.fallback = c.fallback.With(fields)
+		return &n
+	}
+	n.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	n.fields = append(n.fields, c.fields...)
+	n.fields = append(n.fields, fields...)
+	return &n
+}
+
+func (c *sdkRouterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.sink == nil {
+		return c.fallback.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sdkRouterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.sink == nil {
+		return c.fallback.Write(ent, fields)
+	}
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return c.sink.write(ent, all)
+}
+
+func (c *sdkRouterCore) Sync() error {
+	if c.sink == nil {
+		return c.fallback.Sync()
+	}
+	return c.sink.core.Sync()
+}
@@ -0,0 +1,91 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// TestSdkLoggerLinkname 官方客户端的sugarBaseLogger消失或类型变化时失败
+func TestSdkLoggerLinkname(t *testing.T) {
+	t.Setenv("mq.consoleAppender.enabled", "true")
+	old := sdkSugarLogger
+	if old == nil {
+		t.Fatal("sdkSugarLogger is nil, linkname target may be missing")
+	}
+	defer func() {
+		sdkSugarLogger = old
+	}()
+	//ResetLogger会重新赋值sugarBaseLogger，linkname指向同一个变量时这里能看到新值
+	rmq_client.ResetLogger()
+	if sdkSugarLogger == nil || sdkSugarLogger == old {
+		t.Fatal("sdkSugarLogger is not linked to the official sugarBaseLogger")
+	}
+	sdkSugarLogger.Desugar().Core().Enabled(0)
+}
+
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *recordHandler) messages(op string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var msgs []string
+	for _, r := range h.records {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == LogKeyOperation && a.Value.String() == op {
+				msgs = append(msgs, r.Message)
+			}
+			return true
+		})
+	}
+	return msgs
+}
+
+func TestSdkLogRouting(t *testing.T) {
+	installSdkLogger()
+	h := &recordHandler{}
+	var debugMsgs []string
+	cases := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"logger", &Config{LogPath: t.TempDir(), Logger: slog.New(h)}},
+		{"debug", &Config{LogPath: t.TempDir(), Debug: true, DebugHandlerFunc: func(msg string) {
+			debugMsgs = append(debugMsgs, msg)
+		}}},
+	}
+	for _, c := range cases {
+		_, err := newSdkClient(c.cfg, func() (struct{}, error) {
+			//模拟官方客户端构造时派生记录器
+			sdkSugarLogger.With(sdkLogClientIdKey, c.name).Info("sdk " + c.name)
+			return struct{}{}, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+	}
+	if msgs := h.messages(opSdk); len(msgs) != 1 || msgs[0] != "sdk logger" {
+		t.Fatalf("Logger got %v, want [sdk logger]", msgs)
+	}
+	if len(debugMsgs) != 0 {
+		t.Fatalf("DebugHandlerFunc got %v, want none", debugMsgs)
+	}
+}
@@ -0,0 +1,172 @@
+package rocketmq_client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"github.com/apache/rocketmq-clients/golang/v5/pkg/utils"
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"os"
+	"slices"
+	"sync"
+)
+
+// TLSConfig 连接服务端的TLS配置，为nil时使用官方客户端的默认配置（TLS且不校验服务端证书）
+type TLSConfig struct {
+	CAFile             string `json:"caFile"`             //CA证书文件，可选，为空时使用系统CA
+	CertFile           string `json:"certFile"`           //客户端证书文件，mTLS时和KeyFile一起设置
+	KeyFile            string `json:"keyFile"`            //客户端私钥文件，mTLS时和CertFile一起设置
+	ServerName         string `json:"serverName"`         //校验服务端证书时使用的域名，可选
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"` //不校验服务端证书，仅用于开发环境
+	Plaintext          bool   `json:"plaintext"`          //不使用TLS，如本地代理，开启时不能设置其他TLS配置
+}
+
+// build 校验并生成tls.Config，Plaintext时返回nil
+func (s *TLSConfig) build() (*tls.Config, error) {
+	if s.Plaintext {
+		if s.CAFile != "" || s.CertFile != "" || s.KeyFile != "" || s.ServerName != "" || s.InsecureSkipVerify {
+			return nil, newError(CodeInvalidConfig, ErrMsgTLSPlaintextConflict)
+		}
+		return nil, nil
+	}
+	tc := &tls.Config{
+		ServerName:         s.ServerName,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgTLSFileInvalid, "TLS.CAFile", s.CAFile).wrap(err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, newError(CodeInvalidConfig, ErrMsgTLSFileInvalid, "TLS.CAFile", s.CAFile)
+		}
+	}
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return nil, newError(CodeInvalidConfig, ErrMsgRequired, "TLS.CertFile/TLS.KeyFile")
+	}
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, newError(CodeInvalidConfig, ErrMsgTLSFileInvalid, "TLS.CertFile/TLS.KeyFile", s.CertFile).wrap(err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// connOptions 按TLS配置生成官方客户端的连接选项
+func (s *TLSConfig) connOptions() ([]rmq_client.ConnOption, error) {
+	tc, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	if s.Plaintext {
+		return []rmq_client.ConnOption{
+			rmq_client.WithDialOptions(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		}, nil
+	}
+	return []rmq_client.ConnOption{rmq_client.WithTLSConfig(tc)}, nil
+}
+
+// 官方客户端创建连接时只有地址，没有实例信息，所以同一地址同时只能使用一种TLS配置：
+// 客户端创建时按Endpoint中的地址登记TLS配置（未设置TLS的客户端也会登记），和运行中的其他客户端冲突时创建失败，
+// 注销后释放登记；从这些地址查询到的路由地址沿用查询所用连接的TLS配置。
+
+// endpointTLS 一个地址登记的TLS配置
+type endpointTLS struct {
+	profile TLSConfig //为零值且tls为false时为官方客户端的默认配置
+	tls     bool
+	opts    []rmq_client.ConnOption
+	refs    int //登记该地址的运行中客户端数，路由地址为0
+}
+
+func (e *endpointTLS) same(o *endpointTLS) bool {
+	return e.tls == o.tls && e.profile == o.profile
+}
+
+var (
+	endpointTLSMu sync.Mutex
+	endpointTLSs  = map[string]*endpointTLS{} //按地址(host:port)登记的TLS配置
+)
+
+// registerEndpointTLS 登记cfg中每个地址的TLS配置，和运行中客户端登记的配置冲突时返回错误，返回释放登记的方法
+func registerEndpointTLS(cfg *Config) (release func(), err error) {
+	entry := &endpointTLS{}
+	if cfg.TLS != nil {
+		if entry.opts, err = cfg.TLS.connOptions(); err != nil {
+			return nil, err
+		}
+		entry.profile, entry.tls = *cfg.TLS, true
+	}
+	endpoints, err := utils.ParseTarget(cfg.Endpoint)
+	if err != nil {
+		return nil, newError(CodeInvalidConfig, ErrMsgInvalidEndpoint, cfg.Endpoint).wrap(err)
+	}
+	var addresses []string
+	for _, address := range endpoints.GetAddresses() {
+		if target := utils.ParseAddress(address); !slices.Contains(addresses, target) {
+			addresses = append(addresses, target)
+		}
+	}
+	endpointTLSMu.Lock()
+	defer endpointTLSMu.Unlock()
+	for _, target := range addresses {
+		if e, ok := endpointTLSs[target]; ok && e.refs > 0 && !e.same(entry) {
+			return nil, newError(CodeInvalidConfig, ErrMsgTLSConflict, target)
+		}
+	}
+	for _, target := range addresses {
+		e, ok := endpointTLSs[target]
+		if !ok || !e.same(entry) {
+			e = &endpointTLS{profile: entry.profile, tls: entry.tls, opts: entry.opts}
+			endpointTLSs[target] = e
+		}
+		e.refs++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			endpointTLSMu.Lock()
+			defer endpointTLSMu.Unlock()
+			for _, target := range addresses {
+				if e, ok := endpointTLSs[target]; ok && e.refs > 0 {
+					e.refs--
+				}
+			}
+		})
+	}, nil
+}
+
+// registerRouteTLS 路由查询结果中的地址沿用查询所用连接(target)的TLS配置，已被客户端登记的地址不变
+func registerRouteTLS(target string, queues []*v2.MessageQueue) {
+	endpointTLSMu.Lock()
+	defer endpointTLSMu.Unlock()
+	from, ok := endpointTLSs[target]
+	if !ok {
+		return
+	}
+	for _, queue := range queues {
+		for _, address := range queue.GetBroker().GetEndpoints().GetAddresses() {
+			addr := utils.ParseAddress(address)
+			if e, ok := endpointTLSs[addr]; ok && (e.refs > 0 || e.same(from)) {
+				continue
+			}
+			endpointTLSs[addr] = &endpointTLS{profile: from.profile, tls: from.tls, opts: from.opts}
+		}
+	}
+}
+
+// rpcClientConnOptions 获取地址对应的连接选项
+func rpcClientConnOptions(target string) []rmq_client.ConnOption {
+	endpointTLSMu.Lock()
+	defer endpointTLSMu.Unlock()
+	if e, ok := endpointTLSs[target]; ok {
+		return slices.Clone(e.opts)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package rocketmq_client
+
+import (
+	v2 "github.com/apache/rocketmq-clients/golang/v5/protocol/v2"
+	"testing"
+)
+
+func TestRegisterEndpointTLSConflict(t *testing.T) {
+	plaintext := &Config{Endpoint: "10.1.0.1:8081;10.1.0.2:8081", TLS: &TLSConfig{Plaintext: true}}
+	releaseA, err := registerEndpointTLS(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rpcClientConnOptions("10.1.0.2:8081")) != 1 {
+		t.Fatal("plaintext conn options not registered")
+	}
+	//同一地址使用官方默认配置的客户端和运行中的明文客户端冲突
+	if _, err = registerEndpointTLS(&Config{Endpoint: "10.1.0.2:8081"}); ErrorCodeOf(err) != CodeInvalidConfig {
+		t.Fatalf("conflict err = %v", err)
+	}
+	releaseB, err := registerEndpointTLS(&Config{Endpoint: "10.1.0.1:8081", TLS: &TLSConfig{Plaintext: true}})
+	if err != nil {
+		t.Fatalf("same TLS settings rejected: %v", err)
+	}
+	releaseA()
+	releaseA()
+	if _, err = registerEndpointTLS(&Config{Endpoint: "10.1.0.1:8081"}); err == nil {
+		t.Fatal("conflict not detected while another client still uses the address")
+	}
+	releaseB()
+	releaseC, err := registerEndpointTLS(&Config{Endpoint: "10.1.0.1:8081"})
+	if err != nil {
+		t.Fatalf("register after release: %v", err)
+	}
+	defer releaseC()
+	if opts := rpcClientConnOptions("10.1.0.1:8081"); len(opts) != 0 {
+		t.Fatalf("default settings got %d conn options", len(opts))
+	}
+}
+
+func TestRegisterRouteTLS(t *testing.T) {
+	release, err := registerEndpointTLS(&Config{Endpoint: "10.2.0.1:8081", TLS: &TLSConfig{Plaintext: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	queues := []*v2.MessageQueue{{
+		Broker: &v2.Broker{Endpoints: &v2.Endpoints{Addresses: []*v2.Address{{Host: "10.2.0.9", Port: 8081}}}},
+	}}
+	if len(rpcClientConnOptions("10.2.0.9:8081")) != 0 {
+		t.Fatal("route address registered before routing")
+	}
+	registerRouteTLS("10.2.0.1:8081", queues)
+	if len(rpcClientConnOptions("10.2.0.9:8081")) != 1 {
+		t.Fatal("route address does not inherit TLS settings")
+	}
+	//未登记的连接查询到的路由不登记
+	registerRouteTLS("10.2.0.2:8081", []*v2.MessageQueue{{
+		Broker: &v2.Broker{Endpoints: &v2.Endpoints{Addresses: []*v2.Address{{Host: "10.2.0.8", Port: 8081}}}},
+	}})
+	if len(rpcClientConnOptions("10.2.0.8:8081")) != 0 {
+		t.Fatal("route from unknown connection registered")
+	}
+}
@@ -0,0 +1,193 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TopicRegistry 主题注册表，记录主题及其类型，发送时按主题查找类型
+type TopicRegistry struct {
+	mu     sync.RWMutex
+	topics map[string]TopicType
+}
+
+// NewTopicRegistry 创建主题注册表，topics为初始的主题及其类型，可为空
+func NewTopicRegistry(topics map[string]TopicType) (*TopicRegistry, error) {
+	r := &TopicRegistry{topics: make(map[string]TopicType, len(topics))}
+	for topic, topicType := range topics {
+		if err := r.Register(topic, topicType); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register 注册主题，已注册的主题会覆盖类型；类型不区分大小写，为空时为NORMAL
+func (r *TopicRegistry) Register(topic string, topicType TopicType) error {
+	if strings.TrimSpace(topic) == "" {
+		return newError(CodeInvalidArgument, ErrMsgRequired, "topic")
+	}
+	topicType = normalizeTopicType(topicType)
+	if !isValidTopicType(topicType) {
+		return newError(CodeInvalidArgument, ErrMsgInvalidTopicType, topic, topicType)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topics[topic] = topicType
+	return nil
+}
+
+// TopicType 获取主题的类型，未注册时ok为false
+func (r *TopicRegistry) TopicType(topic string) (topicType TopicType, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	topicType, ok = r.topics[topic]
+	return
+}
+
+// Topics 所有已注册的主题及其类型
+func (r *TopicRegistry) Topics() map[string]TopicType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.topics)
+}
+
+// lookup 获取主题的类型，未注册时返回ErrTopicNotRegistered
+func (r *TopicRegistry) lookup(topic string) (TopicType, error) {
+	topicType, ok := r.TopicType(topic)
+	if !ok {
+		return "", newError(CodeTopicNotRegistered, ErrMsgTopicNotRegistered, topic)
+	}
+	return topicType, nil
+}
+
+// check 校验生产者选项是否覆盖了所有已注册的主题，且选项中声明的主题类型和注册的一致
+func (r *TopicRegistry) check(options *ProducerOptions) error {
+	var missing []string
+	for topic, topicType := range r.Topics() {
+		if !slices.Contains(options.Topics, topic) {
+			missing = append(missing, topic)
+			continue
+		}
+		if t, ok := options.TopicTypes[topic]; ok && normalizeTopicType(t) != topicType {
+			return newError(CodeInvalidConfig, ErrMsgTopicTypeConflict, topic, topicType, t)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return newError(CodeInvalidConfig, ErrMsgTopicsNotCovered, strings.Join(missing, ","))
+	}
+	return nil
+}
+
+// TopicRegistry 按实例配置中producer.topics创建主题注册表，未配置producer时返回空的注册表
+func (s *InstanceConfig) TopicRegistry() (*TopicRegistry, error) {
+	if s.Producer == nil {
+		return NewTopicRegistry(nil)
+	}
+	return NewTopicRegistry(s.Producer.Topics)
+}
+
+// TopicProducer 按主题注册表自动确定消息类型的生产者
+type TopicProducer interface {
+	Stop() error                                                                         //注销生产者
+	Send(ctx context.Context, msg Message) (resp []*rmq_client.SendReceipt, err error)   //同步发送消息
+	SendAsync(ctx context.Context, msg Message, dealFunc SendAsyncDealFunc) error        //异步发送消息
+	SendTransaction(ctx context.Context, message Message, confirmFunc ConfirmFunc) error //发送事务消息
+	Registry() *TopicRegistry                                                            //主题注册表
+}
+
+// GetTopicProducer 获取按主题注册表发送的生产者
+// 启动前会校验生产者的主题列表（WithProducerOptionTopics、WithProducerOptionTopicTypes）是否覆盖了所有已注册的主题；
+// 发送时按主题查找类型，未注册的主题直接返回ErrTopicNotRegistered
+func GetTopicProducer(cfg *Config, registry *TopicRegistry, oFunc ...ProducerOptionFunc) (producer TopicProducer, err error) {
+	return getTopicProducer(cfg, registry, GetProducer, oFunc...)
+}
+
+func getTopicProducer(cfg *Config, registry *TopicRegistry, getProducer func(cfg *Config, oFunc ...ProducerOptionFunc) (Producer, error), oFunc ...ProducerOptionFunc) (producer TopicProducer, err error) {
+	if registry == nil {
+		err = newError(CodeInvalidArgument, ErrMsgRequired, "registry")
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStart, "生产者参数不合法", attrError(err))
+		return
+	}
+	if err = registry.check(newProducerOptions(oFunc...)); err != nil {
+		logEvent(context.Background(), cfg, slog.LevelError, opProducerStart, "生产者参数不合法", attrError(err))
+		return
+	}
+	p, err := getProducer(cfg, oFunc...)
+	if err != nil {
+		return
+	}
+	producer = NewTopicProducer(cfg, p, registry)
+	return
+}
+
+// NewTopicProducer 用主题注册表包装已有的生产者，如故障切换的生产者，不会校验生产者的主题列表
+func NewTopicProducer(cfg *Config, producer Producer, registry *TopicRegistry) TopicProducer {
+	return &topicProducer{
+		cfg:      cfg,
+		producer: producer,
+		registry: registry,
+	}
+}
+
+type topicProducer struct {
+	cfg      *Config
+	producer Producer
+	registry *TopicRegistry
+}
+
+func (s *topicProducer) Registry() *TopicRegistry {
+	return s.registry
+}
+
+// Health 被包装的生产者的健康状态
+func (s *topicProducer) Health() ClientHealth {
+	if reporter, ok := s.producer.(HealthReporter); ok {
+		return reporter.Health()
+	}
+	return ClientHealth{Kind: healthKindProducer, Circuit: CircuitClosed}
+}
+
+func (s *topicProducer) Stop() error {
+	return s.producer.Stop()
+}
+
+// Send 同步发送消息，类型为主题注册的类型，不支持事务消息
+func (s *topicProducer) Send(ctx context.Context, msg Message) (resp []*rmq_client.SendReceipt, err error) {
+	topicType, err := s.registry.lookup(msg.Topic)
+	if err != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opSend, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	return s.producer.Send(ctx, topicType, msg)
+}
+
+// SendAsync 异步发送消息，类型为主题注册的类型，不支持事务消息
+func (s *topicProducer) SendAsync(ctx context.Context, msg Message, dealFunc SendAsyncDealFunc) (err error) {
+	topicType, err := s.registry.lookup(msg.Topic)
+	if err != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opSendAsync, "消息发送失败", attrTopic(msg.Topic), attrError(err))
+		return
+	}
+	return s.producer.SendAsync(ctx, topicType, msg, dealFunc)
+}
+
+// SendTransaction 发送事务消息，主题需注册为TRANSACTION类型
+func (s *topicProducer) SendTransaction(ctx context.Context, message Message, confirmFunc ConfirmFunc) (err error) {
+	topicType, err := s.registry.lookup(message.Topic)
+	if err == nil && topicType != TopicTransaction {
+		err = newError(CodeTopicTypeMismatch, ErrMsgTopicTypeMismatch, message.Topic, topicType, TopicTransaction)
+	}
+	if err != nil {
+		logEvent(ctx, s.cfg, slog.LevelError, opSendTx, "消息发送失败", attrTopic(message.Topic), attrError(err))
+		return
+	}
+	return s.producer.SendTransaction(ctx, message, confirmFunc)
+}
@@ -0,0 +1,119 @@
+package rocketmq_client
+
+import (
+	"context"
+	rmq_client "github.com/apache/rocketmq-clients/golang/v5"
+	"testing"
+)
+
+func TestTopicRegistryRegister(t *testing.T) {
+	r, err := NewTopicRegistry(map[string]TopicType{"t_fifo": " fifo ", "t_empty": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Register("t_delay", "Delay"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]TopicType{"t_fifo": TopicFIFO, "t_empty": TopicNormal, "t_delay": TopicDelay}
+	for topic, topicType := range want {
+		if got, ok := r.TopicType(topic); !ok || got != topicType {
+			t.Errorf("TopicType(%s) = %q, %v, want %q", topic, got, ok, topicType)
+		}
+	}
+	if err = r.Register(" ", TopicNormal); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("empty topic = %v", err)
+	}
+	if err = r.Register("t", "queue"); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("invalid type = %v", err)
+	}
+}
+
+func TestInstanceConfigTopicRegistry(t *testing.T) {
+	cfgs, err := LoadConfigContent([]byte(testConfigYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := cfgs["default"].TopicRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]TopicType{"t_normal": TopicNormal, "t_empty": TopicNormal, "t_fifo": TopicFIFO}
+	if got := r.Topics(); len(got) != len(want) {
+		t.Fatalf("Topics = %v, want %v", got, want)
+	}
+	for topic, topicType := range want {
+		if got, _ := r.TopicType(topic); got != topicType {
+			t.Errorf("TopicType(%s) = %q, want %q", topic, got, topicType)
+		}
+	}
+	if r, err = (&InstanceConfig{}).TopicRegistry(); err != nil || len(r.Topics()) != 0 {
+		t.Fatalf("registry without producer = %v, %v", r.Topics(), err)
+	}
+}
+
+func TestGetTopicProducerCheck(t *testing.T) {
+	r, _ := NewTopicRegistry(map[string]TopicType{"a": TopicFIFO, "b": TopicNormal})
+	var started int
+	getProducer := func(*Config, ...ProducerOptionFunc) (Producer, error) {
+		started++
+		return &fakeProducer{}, nil
+	}
+	cases := map[string][]ProducerOptionFunc{
+		"not covered":   {WithProducerOptionTopics("a")},
+		"type conflict": {WithProducerOptionTopicTypes(map[string]TopicType{"a": TopicNormal, "b": TopicNormal})},
+	}
+	for name, oFunc := range cases {
+		if _, err := getTopicProducer(&Config{}, r, getProducer, oFunc...); ErrorCodeOf(err) != CodeInvalidConfig {
+			t.Errorf("%s: err = %v, want invalid config", name, err)
+		}
+	}
+	if _, err := getTopicProducer(&Config{}, nil, getProducer); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("nil registry: err = %v", err)
+	}
+	if started != 0 {
+		t.Fatalf("producer started %d times for invalid options", started)
+	}
+	//选项中的类型不区分大小写
+	if _, err := getTopicProducer(&Config{}, r, getProducer, WithProducerOptionTopicTypes(map[string]TopicType{"a": "fifo", "b": TopicNormal})); err != nil || started != 1 {
+		t.Fatalf("covered topics: err = %v, started %d", err, started)
+	}
+}
+
+func TestTopicProducerSend(t *testing.T) {
+	r, _ := NewTopicRegistry(map[string]TopicType{"t_fifo": TopicFIFO, "t_delay": TopicDelay, "t_tx": TopicTransaction})
+	ctx := context.Background()
+
+	//未注册的主题不交给生产者
+	fake := &fakeProducer{}
+	p := NewTopicProducer(&Config{}, fake, r)
+	msg := Message{Topic: "unknown", Body: "x"}
+	if _, err := p.Send(ctx, msg); ErrorCodeOf(err) != CodeTopicNotRegistered {
+		t.Errorf("Send unregistered = %v", err)
+	}
+	if err := p.SendAsync(ctx, msg, nil); ErrorCodeOf(err) != CodeTopicNotRegistered {
+		t.Errorf("SendAsync unregistered = %v", err)
+	}
+	if err := p.SendTransaction(ctx, msg, nil); ErrorCodeOf(err) != CodeTopicNotRegistered {
+		t.Errorf("SendTransaction unregistered = %v", err)
+	}
+	if err := p.SendTransaction(ctx, Message{Topic: "t_fifo", Body: "x"}, nil); ErrorCodeOf(err) != CodeTopicTypeMismatch {
+		t.Errorf("SendTransaction to fifo topic = %v", err)
+	}
+	if fake.count() != 0 {
+		t.Fatalf("sends = %d, want 0", fake.count())
+	}
+	if _, err := p.Send(ctx, Message{Topic: "t_fifo", Body: "x", MessageGroup: "g"}); err != nil || fake.count() != 1 {
+		t.Fatalf("Send registered = %v, sends %d", err, fake.count())
+	}
+
+	//按注册的类型校验消息，校验不通过时不会调用官方客户端
+	p = NewTopicProducer(&Config{}, &defaultProducer{Cfg: &Config{}, producer: struct{ rmq_client.Producer }{}, stats: newClientStats(healthKindProducer)}, r)
+	for _, msg := range []Message{
+		{Topic: "t_fifo", Body: "x"},
+		{Topic: "t_delay", Body: "x"},
+	} {
+		if _, err := p.Send(ctx, msg); ErrorCodeOf(err) != CodeInvalidMessage {
+			t.Errorf("Send %s without required fields = %v, want invalid message", msg.Topic, err)
+		}
+	}
+}